@@ -2,14 +2,12 @@ package app
 
 import (
 	"bytes"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/tendermint/tendermint/abci/types"
-	"golang.org/x/crypto/ed25519"
 
 	"github.com/saguywalker/sitcomchain/code"
 	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
@@ -44,7 +42,6 @@ func (a *SitcomApplication) CheckTx(req types.RequestCheckTx) (res types.Respons
 	}
 
 	payload := txObj.Payload
-	signature := txObj.Signature
 
 	if payload.Method == "" {
 		a.logger.Infoln("method cannot be empty")
@@ -60,33 +57,16 @@ func (a *SitcomApplication) CheckTx(req types.RequestCheckTx) (res types.Respons
 		return
 	}
 
-	if payload.Method == "GiveBadge" || payload.Method == "ApproveActivity" {
-		b64PubKey := a.state.db.Get([]byte("sitcompetence"))
-		if b64PubKey == nil {
-			res.Code = code.CodeTypeUnauthorized
-			res.Log = "sitcompetence publickey not found"
-			a.logger.Infoln(res.Log)
-			return
-		}
+	if _, res.Code, res.Log = a.verifyTx(&txObj); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return
+	}
 
-		publicKey, err := base64.StdEncoding.DecodeString(string(b64PubKey))
-		if err != nil {
-			res.Code = code.CodeTypeDecodingError
-			res.Log = "decoding error"
+	if payload.Method == "SetValidator" {
+		if res.Code, res.Log = a.checkValidatorParams(payload.Params); res.Code != code.CodeTypeOK {
 			a.logger.Infoln(res.Log)
 			return
 		}
-
-		a.logger.Infof("params: %x", string(payload.Params))
-		hashed := sha256.Sum256(payload.Params)
-
-		if !ed25519.Verify(publicKey, hashed[:], signature) {
-			a.logger.Errorf("Failed in signature verification\n")
-			res.Code = code.CodeTypeUnauthorized
-			res.Log = "failed in signature verification"
-			return
-		}
-
 	}
 
 	res.Code = code.CodeTypeOK
@@ -113,19 +93,146 @@ func (a *SitcomApplication) DeliverTx(req types.RequestDeliverTx) (res types.Res
 			Log:  err.Error()}
 	}
 
+	var signers [][]byte
+	if signers, res.Code, res.Log = a.verifyTx(&txObj); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return
+	}
+
 	payload := txObj.Payload
-	var err error
+	a.useNonce(payload)
+
+	// a transaction which fails halfway leaves none of its writes behind,
+	// its nonce stays used
+	a.atomic(func() error {
+		var err error
+		if res, err = a.deliverPayload(payload, &txObj, signers); err == nil && res.Code != code.CodeTypeOK {
+			err = errors.New(res.Log)
+		}
+		return err
+	})
+	return res
+}
+
+// deliverPayload execute a verified payload, txObj is the transaction
+// which carries its signatures
+func (a *SitcomApplication) deliverPayload(payload *protoTm.Payload, txObj *protoTm.Tx, signers [][]byte) (res types.ResponseDeliverTx, err error) {
 	switch payload.Method {
 	case "SetValidator":
 		res = a.setValidator(string(payload.Params))
 		a.state.Size++
 	case "GiveBadge":
-		res, err = a.giveBadge(payload.Params)
+		res, err = a.giveBadge(payload.Params, txObj, signers)
+		if err != nil {
+			return
+		}
+	case "GiveBadgeBatch":
+		res, err = a.giveBadgeBatch(payload.Params, txObj, signers)
 		if err != nil {
 			return
 		}
 	case "ApproveActivity":
-		res, err = a.approveActivity(payload.Params)
+		res, err = a.approveActivity(payload.Params, txObj, signers)
+		if err != nil {
+			return
+		}
+	case "CreateMultisig":
+		res, err = a.createMultisig(payload.Params)
+		if err != nil {
+			return
+		}
+	case "SetSignPolicy":
+		res, err = a.setSignPolicy(payload.Params)
+		if err != nil {
+			return
+		}
+	case "AssignRole":
+		res, err = a.assignRole(payload.Params)
+		if err != nil {
+			return
+		}
+	case "ProposeBadge":
+		res, err = a.proposeBadge(payload.Params, signers)
+		if err != nil {
+			return
+		}
+	case "ApproveProposal":
+		res, err = a.approveProposal(payload.Params, txObj, signers)
+		if err != nil {
+			return
+		}
+	case "RegisterIdentity":
+		res, err = a.registerIdentity(payload.Params)
+		if err != nil {
+			return
+		}
+	case "RotateKey":
+		res, err = a.rotateKey(payload.Params)
+		if err != nil {
+			return
+		}
+	case "RevokeKey":
+		res, err = a.revokeKey(payload.Params)
+		if err != nil {
+			return
+		}
+	case "CreateCompetence":
+		res, err = a.createCompetence(payload.Params)
+		if err != nil {
+			return
+		}
+	case "UpdateCompetence":
+		res, err = a.updateCompetence(payload.Params)
+		if err != nil {
+			return
+		}
+	case "DeprecateCompetence":
+		res, err = a.deprecateCompetence(payload.Params)
+		if err != nil {
+			return
+		}
+	case "CreateActivity":
+		res, err = a.createActivity(payload.Params, signers)
+		if err != nil {
+			return
+		}
+	case "SetActivityState":
+		res, err = a.setActivityState(payload.Params)
+		if err != nil {
+			return
+		}
+	case "EnrollActivity":
+		res, err = a.enrollActivity(payload.Params, signers)
+		if err != nil {
+			return
+		}
+	case "RecordAttendance":
+		res, err = a.recordAttendance(payload.Params, signers)
+		if err != nil {
+			return
+		}
+	case "CreateSemester":
+		res, err = a.createSemester(payload.Params)
+		if err != nil {
+			return
+		}
+	case "UpdateSemester":
+		res, err = a.updateSemester(payload.Params)
+		if err != nil {
+			return
+		}
+	case "SetStudentRecord":
+		res, err = a.setStudentRecord(payload.Params)
+		if err != nil {
+			return
+		}
+	case "ForgetStudent":
+		res, err = a.forgetStudent(payload.Params)
+		if err != nil {
+			return
+		}
+	case MultiMessageMethod:
+		res, err = a.deliverMessages(payload, txObj)
 		if err != nil {
 			return
 		}
@@ -139,7 +246,7 @@ func (a *SitcomApplication) DeliverTx(req types.RequestDeliverTx) (res types.Res
 		res.Code = code.CodeTypeInvalidMethod
 	}
 
-	return res
+	return res, err
 }
 
 // Commit commit a current transaction batch
@@ -163,7 +270,57 @@ func (a *SitcomApplication) Query(req types.RequestQuery) (res types.ResponseQue
 		}
 	}()
 
-	a.logger.Infof("In query: %s\n", string(req.Data))
+	// data may identify a student, it is never logged
+	a.logger.Infof("In query: %s\n", req.Path)
+
+	switch req.Path {
+	case "/proposal":
+		return a.queryProposal(req.Data)
+	case "/proposals":
+		return a.queryProposals(req.Data)
+	case "/identity":
+		return a.queryIdentity(req.Data)
+	case "/verify_badge":
+		return a.queryVerifyBadge(req.Data)
+	case "/revoked":
+		return a.queryRevoked(req.Data)
+	case "/competence":
+		return a.queryCompetence(req.Data)
+	case "/competences":
+		return a.queryCompetences(req.Data)
+	case "/activity":
+		return a.queryActivity(req.Data)
+	case "/activities":
+		return a.queryActivities(req.Data)
+	case "/enrollments":
+		return a.queryEnrollments(req.Data)
+	case "/progress":
+		return a.queryProgress(req.Data)
+	case "/semester":
+		return a.querySemester(req.Data)
+	case "/semesters":
+		return a.querySemesters(req.Data)
+	case "/semester_badges":
+		return a.querySemesterBadges(req.Data)
+	case "/semester_stats":
+		return a.querySemesterStats(req.Data)
+	case "/portfolio":
+		return a.queryPortfolio(req.Data)
+	case "/student_record":
+		return a.queryStudentRecord(req.Data)
+	case "/credential":
+		return a.queryCredential(req.Data)
+	case "/openbadges":
+		return a.queryOpenBadges(req.Data)
+	case "/transcript":
+		return a.queryTranscript(req.Data)
+	case "/badge_stats":
+		return a.queryBadgeStats(req.Data)
+	case "/participation":
+		return a.queryParticipation(req.Data)
+	case "/top_issuers":
+		return a.queryTopIssuers(req.Data)
+	}
 
 	if len(req.Data) == 0 {
 		itr := a.state.db.Iterator(nil, nil)
@@ -181,6 +338,9 @@ func (a *SitcomApplication) Query(req types.RequestQuery) (res types.ResponseQue
 		itr := a.state.db.Iterator(nil, nil)
 		for ; itr.Valid(); itr.Next() {
 			key := itr.Key()
+			if isStudentKey(key) {
+				continue
+			}
 			if bytes.Contains(key, parts[0]) && bytes.Contains(key, parts[1]) {
 				result = append(result, []byte("|")...)
 				result = append(result, key...)
@@ -198,7 +358,13 @@ func (a *SitcomApplication) Query(req types.RequestQuery) (res types.ResponseQue
 
 	}
 
-	// For verify
+	// For verify, a badge key can be checked but a student's indexes are not served
+	if isStudentIndex(req.Data) {
+		res.Code = code.CodeTypeUnauthorized
+		res.Log = "a student's badges are listed only with a student proof"
+		return
+	}
+
 	value := a.state.db.Get(req.Data)
 	if value != nil {
 		res.Log = "exists"
@@ -212,6 +378,10 @@ func (a *SitcomApplication) Query(req types.RequestQuery) (res types.ResponseQue
 
 // InitChain is used for initialize a blockchain
 func (a *SitcomApplication) InitChain(req types.RequestInitChain) types.ResponseInitChain {
+	a.state.ChainID = req.ChainId
+	if req.ConsensusParams != nil && req.ConsensusParams.Validator != nil {
+		a.state.ValidatorKeyTypes = req.ConsensusParams.Validator.PubKeyTypes
+	}
 	for _, v := range req.Validators {
 		r := a.updateValidator(v)
 		if r.IsErr() {
@@ -226,6 +396,8 @@ func (a *SitcomApplication) BeginBlock(req types.RequestBeginBlock) types.Respon
 	a.logger.Infof("BeginBlock: %d, ChainID: %s", req.Header.Height, req.Header.ChainID)
 	a.state.Height = req.Header.Height
 	a.CurrentChain = req.Header.ChainID
+	a.state.ChainID = req.Header.ChainID
+	a.state.BlockTime = req.Header.Time.Unix()
 	a.valUpdates = make(map[string]types.ValidatorUpdate, 0)
 	return types.ResponseBeginBlock{}
 }
@@ -0,0 +1,286 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// ActivityPrefix define the prefix of activity keys
+	ActivityPrefix string = "activity:"
+
+	// ActivityDraft is an activity which is being prepared
+	ActivityDraft string = "draft"
+
+	// ActivityOpen is an activity which students can join
+	ActivityOpen string = "open"
+
+	// ActivityClosed is an activity which has ended
+	ActivityClosed string = "closed"
+
+	// ActivityFinalized is an activity whose approvals are complete
+	ActivityFinalized string = "finalized"
+)
+
+// activityNextState define allowed transitions of an activity
+var activityNextState = map[string]string{
+	ActivityDraft:  ActivityOpen,
+	ActivityOpen:   ActivityClosed,
+	ActivityClosed: ActivityFinalized,
+}
+
+func activityKey(id uint32) []byte {
+	return []byte(ActivityPrefix + strconv.FormatUint(uint64(id), 10))
+}
+
+func (a *SitcomApplication) getActivity(id uint32) (*Activity, error) {
+	value := a.state.db.Get(activityKey(id))
+	if value == nil {
+		return nil, fmt.Errorf("activity %d not found", id)
+	}
+
+	var activity Activity
+	if err := json.Unmarshal(value, &activity); err != nil {
+		return nil, err
+	}
+
+	return &activity, nil
+}
+
+func (a *SitcomApplication) setActivity(activity *Activity) error {
+	value, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(activityKey(activity.ID), value)
+	return nil
+}
+
+// isManagedBy report whether the public key is the organizer or a delegate
+func (activity *Activity) isManagedBy(publicKey []byte) bool {
+	if bytes.Equal(activity.Organizer, publicKey) {
+		return true
+	}
+
+	for _, delegate := range activity.Delegates {
+		if bytes.Equal(delegate, publicKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkActivitySignature verify that the organizer or a delegate of
+// the activity in params signed the transaction
+func (a *SitcomApplication) checkActivitySignature(payload *protoTm.Payload, signBytes []byte, signatures []*protoTm.Signature) ([][]byte, uint32, string) {
+	var param ActivityStateParam
+	if err := json.Unmarshal(payload.Params, &param); err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal params"
+	}
+
+	activity, err := a.getActivity(param.ActivityID)
+	if err != nil {
+		return nil, code.CodeTypeNotFound, err.Error()
+	}
+
+	for _, sig := range signatures {
+		if activity.isManagedBy(sig.PublicKey) && !a.isRevoked(sig.PublicKey) && verifySignature(sig.KeyType, sig.PublicKey, signBytes, sig.Signature) {
+			return [][]byte{sig.PublicKey}, code.CodeTypeOK, ""
+		}
+	}
+
+	return nil, code.CodeTypeUnauthorized, fmt.Sprintf("requires a signature from organizer or delegate of activity %d", activity.ID)
+}
+
+func (a *SitcomApplication) createActivity(payload []byte, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var activity Activity
+	if err := json.Unmarshal(payload, &activity); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if activity.ID == 0 || activity.Title == "" {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "activity needs an id and a title"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if activity.End < activity.Start {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "activity cannot end before it starts"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if a.state.db.Has(activityKey(activity.ID)) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = fmt.Sprintf("activity %d already exists", activity.ID)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if activity.Semester != 0 {
+		if _, err := a.getSemester(activity.Semester); err != nil {
+			res.Code = code.CodeTypeNotFound
+			res.Log = err.Error()
+			a.logger.Infoln(res.Log)
+			return res, err
+		}
+	}
+
+	for _, competenceID := range activity.Competences {
+		if res.Code, res.Log = a.checkCompetence(competenceID); res.Code != code.CodeTypeOK {
+			a.logger.Infoln(res.Log)
+			return res, errors.New(res.Log)
+		}
+	}
+
+	if activity.Delegates == nil {
+		activity.Delegates = make([][]byte, 0)
+	}
+	if activity.Competences == nil {
+		activity.Competences = make([]uint32, 0)
+	}
+	if activity.Tags == nil {
+		activity.Tags = make([]string, 0)
+	}
+	activity.Organizer = signers[0]
+	activity.State = ActivityDraft
+	activity.Enrolled = 0
+	activity.CreatedHeight = a.state.Height
+	activity.UpdatedHeight = a.state.Height
+	if err := a.setActivity(&activity); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal activity"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) setActivityState(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var param ActivityStateParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	activity, err := a.getActivity(param.ActivityID)
+	if err != nil {
+		res.Code = code.CodeTypeNotFound
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if activityNextState[activity.State] != param.State {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = fmt.Sprintf("activity %d cannot move from %s to %s", activity.ID, activity.State, param.State)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	activity.State = param.State
+	activity.UpdatedHeight = a.state.Height
+	if err := a.setActivity(activity); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal activity"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// checkApprovableActivity return an error code unless the activity is open or closed
+func (a *SitcomApplication) checkApprovableActivity(id uint32) (uint32, string) {
+	activity, err := a.getActivity(id)
+	if err != nil {
+		return code.CodeTypeNotFound, err.Error()
+	}
+
+	if activity.State != ActivityOpen && activity.State != ActivityClosed {
+		return code.CodeTypeInvalidParams, fmt.Sprintf("activity %d is %s", id, activity.State)
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+func (a *SitcomApplication) queryActivity(data []byte) (res types.ResponseQuery) {
+	id, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid activity id"
+		return
+	}
+
+	activity, err := a.getActivity(uint32(id))
+	if err != nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = activityKey(activity.ID)
+	res.Value, err = json.Marshal(activity)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal activity"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+// queryActivities list activities, optionally only in one state
+func (a *SitcomApplication) queryActivities(state []byte) (res types.ResponseQuery) {
+	activities := make([]*Activity, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(ActivityPrefix))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var activity Activity
+		if err := json.Unmarshal(itr.Value(), &activity); err != nil {
+			continue
+		}
+
+		if len(state) == 0 || activity.State == string(state) {
+			activities = append(activities, &activity)
+		}
+	}
+
+	value, err := json.Marshal(activities)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal activities"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = value
+	return
+}
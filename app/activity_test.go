@@ -0,0 +1,77 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func newActivityApp(t *testing.T) (*testApp, ed25519.PrivateKey, ed25519.PrivateKey) {
+	ta := newTestApp(t)
+
+	admin := ta.admin()
+	organizerKey, organizer := ta.newKey()
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: organizerKey, Role: RoleOrganizer}, admin)
+
+	return ta, admin, organizer
+}
+
+func TestActivityLifecycle(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+	delegateKey, delegate := ta.newKey()
+	_, stranger := ta.newKey()
+
+	hackathon := Activity{ID: 1, Title: "Hackathon", Semester: 1, Capacity: 10, Competences: []uint32{1}, Delegates: [][]byte{delegateKey}}
+	ta.must(code.CodeTypeUnauthorized, "CreateActivity", hackathon, stranger)
+	ta.must(code.CodeTypeInvalidParams, "CreateActivity", Activity{ID: 1}, organizer)
+	ta.must(code.CodeTypeInvalidParams, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Start: 10, End: 5}, organizer)
+	ta.must(code.CodeTypeNotFound, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Semester: 2}, organizer)
+	ta.must(code.CodeTypeNotFound, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Competences: []uint32{2}}, organizer)
+	ta.must(code.CodeTypeOK, "CreateActivity", hackathon, organizer)
+	ta.must(code.CodeTypeDuplicateKey, "CreateActivity", hackathon, organizer)
+
+	var activity Activity
+	ta.queryJSON("/activity", "1", &activity)
+	if activity.State != ActivityDraft || !bytes.Equal(activity.Organizer, organizer.Public().(ed25519.PublicKey)) {
+		t.Fatalf("expected a draft of the organizer, got %+v", activity)
+	}
+
+	approval := ApproveActivity{StudentID: ta.student("1"), ActivityID: 1}
+	ta.must(code.CodeTypeInvalidParams, "ApproveActivity", approval, organizer)
+
+	// states only move forward, one step at a time
+	ta.must(code.CodeTypeUnauthorized, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, stranger)
+	ta.must(code.CodeTypeInvalidParams, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityClosed}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+	ta.must(code.CodeTypeInvalidParams, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityDraft}, organizer)
+	ta.must(code.CodeTypeNotFound, "SetActivityState", ActivityStateParam{ActivityID: 2, State: ActivityOpen}, organizer)
+
+	ta.must(code.CodeTypeUnauthorized, "ApproveActivity", approval, stranger)
+	ta.must(code.CodeTypeOK, "ApproveActivity", approval, delegate)
+
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityClosed}, delegate)
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: ta.student("2"), ActivityID: 1}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityFinalized}, organizer)
+	ta.must(code.CodeTypeInvalidParams, "ApproveActivity", ApproveActivity{StudentID: ta.student("3"), ActivityID: 1}, organizer)
+
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 2, Title: "Workshop"}, organizer)
+
+	var activities []Activity
+	ta.queryJSON("/activities", ActivityFinalized, &activities)
+	if len(activities) != 1 || activities[0].ID != 1 {
+		t.Fatalf("expected the finalized activity, got %+v", activities)
+	}
+	ta.queryJSON("/activities", "", &activities)
+	if len(activities) != 2 {
+		t.Fatalf("expected 2 activities, got %+v", activities)
+	}
+	if res := ta.query("/activity", "3"); res.Log != "does not exist" {
+		t.Fatalf("expected no activity 3, got %s", res.Log)
+	}
+}
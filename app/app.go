@@ -5,6 +5,7 @@ import (
 	"github.com/tendermint/tendermint/abci/types"
 	dbm "github.com/tendermint/tm-db"
 
+	"github.com/saguywalker/sitcomchain/pii"
 	"github.com/saguywalker/sitcomchain/version"
 )
 
@@ -19,15 +20,39 @@ type SitcomApplication struct {
 	state              State
 	valUpdates         map[string]types.ValidatorUpdate
 	verifiedSignatures map[string]string
+	pii                *pii.Store
+	pending            *[]func()
+	location           []int
 }
 
 var (
 	_          types.Application = (*SitcomApplication)(nil)
 	methodList                   = map[string]bool{
-		"SetValidator":    true,
-		"AddNewService":   true,
-		"GiveBadge":       true,
-		"ApproveActivity": true,
+		"SetValidator":        true,
+		"AddNewService":       true,
+		"GiveBadge":           true,
+		"ApproveActivity":     true,
+		"CreateMultisig":      true,
+		"SetSignPolicy":       true,
+		"AssignRole":          true,
+		"ProposeBadge":        true,
+		"ApproveProposal":     true,
+		"RegisterIdentity":    true,
+		"RotateKey":           true,
+		"RevokeKey":           true,
+		"CreateCompetence":    true,
+		"UpdateCompetence":    true,
+		"DeprecateCompetence": true,
+		"CreateActivity":      true,
+		"SetActivityState":    true,
+		"EnrollActivity":      true,
+		"RecordAttendance":    true,
+		"CreateSemester":      true,
+		"UpdateSemester":      true,
+		"SetStudentRecord":    true,
+		"ForgetStudent":       true,
+		"GiveBadgeBatch":      true,
+		MultiMessageMethod:    true,
 	}
 )
 
@@ -54,3 +79,9 @@ func NewSitcomApp(dbDir string, logger *logrus.Logger) *SitcomApplication {
 		verifiedSignatures: make(map[string]string),
 	}
 }
+
+// SetPIIStore set the node's off-chain store of personal data,
+// ForgetStudent deletes the student's key from it
+func (a *SitcomApplication) SetPIIStore(store *pii.Store) {
+	a.pii = store
+}
@@ -0,0 +1,232 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/tendermint/tendermint/abci/types"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/student"
+)
+
+const testChainID = "test-chain"
+
+// testApp is an application on a temporary database which delivers
+// transactions signed over the current sign-bytes scheme
+type testApp struct {
+	t     *testing.T
+	dir   string
+	app   *SitcomApplication
+	nonce uint64
+}
+
+func newTestApp(t *testing.T) *testApp {
+	dir, err := ioutil.TempDir("", "sitcomchain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	app := NewSitcomApp(dir, logger)
+	app.InitChain(types.RequestInitChain{ChainId: testChainID})
+	return &testApp{t: t, dir: dir, app: app}
+}
+
+func (ta *testApp) close() {
+	os.RemoveAll(ta.dir)
+}
+
+func (ta *testApp) newKey() ([]byte, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return publicKey, privateKey
+}
+
+// payload build the payload of a method with a new nonce
+func (ta *testApp) payload(method string, params interface{}) *protoTm.Payload {
+	ta.t.Helper()
+
+	var encoded []byte
+	switch value := params.(type) {
+	case string:
+		encoded = []byte(value)
+	default:
+		var err error
+		if encoded, err = json.Marshal(value); err != nil {
+			ta.t.Fatal(err)
+		}
+	}
+
+	ta.nonce++
+	return &protoTm.Payload{
+		Method:  method,
+		Params:  encoded,
+		ChainId: testChainID,
+		Nonce:   []byte(fmt.Sprintf("nonce-%d", ta.nonce)),
+		Version: SignVersion,
+	}
+}
+
+// sign return ed25519 signatures of every key over the payload
+func (ta *testApp) sign(payload *protoTm.Payload, keys ...ed25519.PrivateKey) []*protoTm.Signature {
+	ta.t.Helper()
+
+	signBytes, err := SignBytes(payload)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	signatures := make([]*protoTm.Signature, 0, len(keys))
+	for _, key := range keys {
+		signatures = append(signatures, &protoTm.Signature{
+			PublicKey: key.Public().(ed25519.PublicKey),
+			Signature: ed25519.Sign(key, signBytes),
+		})
+	}
+
+	return signatures
+}
+
+func (ta *testApp) encode(txObj *protoTm.Tx) []byte {
+	ta.t.Helper()
+
+	tx, err := proto.Marshal(txObj)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return tx
+}
+
+// tx build a transaction of a method signed by every key
+func (ta *testApp) tx(method string, params interface{}, keys ...ed25519.PrivateKey) []byte {
+	ta.t.Helper()
+
+	payload := ta.payload(method, params)
+	return ta.encode(&protoTm.Tx{Payload: payload, Signatures: ta.sign(payload, keys...)})
+}
+
+// deliverTx check a transaction and deliver it when it passes
+func (ta *testApp) deliverTx(tx []byte) types.ResponseDeliverTx {
+	if res := ta.app.CheckTx(types.RequestCheckTx{Tx: tx}); res.Code != 0 {
+		return types.ResponseDeliverTx{Code: res.Code, Log: res.Log}
+	}
+
+	return ta.app.DeliverTx(types.RequestDeliverTx{Tx: tx})
+}
+
+// deliver check a transaction of a method signed by every key and deliver it when it passes
+func (ta *testApp) deliver(method string, params interface{}, keys ...ed25519.PrivateKey) types.ResponseDeliverTx {
+	ta.t.Helper()
+
+	return ta.deliverTx(ta.tx(method, params, keys...))
+}
+
+// must deliver a transaction and fail the test unless it ends with the code
+func (ta *testApp) must(expected uint32, method string, params interface{}, keys ...ed25519.PrivateKey) types.ResponseDeliverTx {
+	ta.t.Helper()
+
+	res := ta.deliver(method, params, keys...)
+	if res.Code != expected {
+		ta.t.Fatalf("%s: expected code %d, got %d: %s", method, expected, res.Code, res.Log)
+	}
+
+	return res
+}
+
+// block begin a block at the height, a block is five seconds after the previous
+func (ta *testApp) block(height int64) {
+	ta.app.BeginBlock(types.RequestBeginBlock{Header: types.Header{
+		ChainID: testChainID,
+		Height:  height,
+		Time:    time.Unix(height*5, 0),
+	}})
+}
+
+func (ta *testApp) query(path string, data string) types.ResponseQuery {
+	return ta.app.Query(types.RequestQuery{Path: path, Data: []byte(data)})
+}
+
+func (ta *testApp) queryJSON(path string, data string, value interface{}) {
+	ta.t.Helper()
+
+	res := ta.query(path, data)
+	if res.Code != code.CodeTypeOK {
+		ta.t.Fatalf("%s %s: %s", path, data, res.Log)
+	}
+
+	if err := json.Unmarshal(res.Value, value); err != nil {
+		ta.t.Fatal(err)
+	}
+}
+
+// admin set a new sitcompetence key and return it
+func (ta *testApp) admin() ed25519.PrivateKey {
+	ta.t.Helper()
+
+	publicKey, privateKey := ta.newKey()
+	ta.must(code.CodeTypeOK, "AddNewService", "sitcompetence:"+base64.StdEncoding.EncodeToString(publicKey))
+	return privateKey
+}
+
+// catalog create competences and an open semester 1 signed by admin
+func (ta *testApp) catalog(admin ed25519.PrivateKey, competences ...Competence) {
+	ta.t.Helper()
+
+	for _, competence := range competences {
+		ta.must(code.CodeTypeOK, "CreateCompetence", competence, admin)
+	}
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 1, Name: "1/2019", Start: 0, End: 1 << 40}, admin)
+}
+
+func (ta *testApp) secret(id string) []byte {
+	return []byte("a secret of the student " + id)
+}
+
+// student return the commitment of a student ID
+func (ta *testApp) student(id string) string {
+	ta.t.Helper()
+
+	commitment, err := student.Commit(id, ta.secret(id))
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return commitment
+}
+
+// studentProof return the query data proving a student ID
+func (ta *testApp) studentProof(id string) string {
+	ta.t.Helper()
+
+	data, err := json.Marshal(StudentProof{StudentID: id, Secret: ta.secret(id)})
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func (ta *testApp) hasBadge(badge GiveBadge) bool {
+	key, err := BadgeKey(&badge)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return ta.app.state.db.Has(key)
+}
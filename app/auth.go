@@ -0,0 +1,234 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/protocol"
+)
+
+const (
+	// SignVersion is the current version of the sign-bytes scheme
+	SignVersion uint32 = 1
+
+	// NoncePrefix define the prefix of used nonce keys
+	NoncePrefix string = "nonce:"
+)
+
+var (
+	adminKey = []byte("sitcompetence")
+)
+
+// signedMethods are methods which must carry a valid signature
+var signedMethods = map[string]bool{
+	"GiveBadge":           true,
+	"ApproveActivity":     true,
+	"AddNewService":       true,
+	"SetValidator":        true,
+	"CreateMultisig":      true,
+	"SetSignPolicy":       true,
+	"AssignRole":          true,
+	"ProposeBadge":        true,
+	"ApproveProposal":     true,
+	"RegisterIdentity":    true,
+	"RotateKey":           true,
+	"RevokeKey":           true,
+	"CreateCompetence":    true,
+	"UpdateCompetence":    true,
+	"DeprecateCompetence": true,
+	"CreateActivity":      true,
+	"SetActivityState":    true,
+	"EnrollActivity":      true,
+	"RecordAttendance":    true,
+	"CreateSemester":      true,
+	"UpdateSemester":      true,
+	"SetStudentRecord":    true,
+	"ForgetStudent":       true,
+	"GiveBadgeBatch":      true,
+	MultiMessageMethod:    true,
+}
+
+// methodRoles are methods signed by a key holding a role instead of sitcompetence
+var methodRoles = map[string]string{
+	"ProposeBadge":    RoleAdvisor,
+	"ApproveProposal": RoleHeadOfDepartment,
+	"CreateActivity":  RoleOrganizer,
+}
+
+// activityMethods are methods signed by the organizer or a delegate of an activity
+var activityMethods = map[string]bool{
+	"ApproveActivity":  true,
+	"SetActivityState": true,
+	"RecordAttendance": true,
+}
+
+// SignBytes return bytes that a signer has to sign for a payload.
+// It covers chain ID, method, nonce, params and scheme version,
+// so a signature cannot be replayed as another method or on another chain.
+// Params of a batch or multi-message are covered by the merkle root of
+// their items, see protocol.SignBytes.
+func SignBytes(payload *protoTm.Payload) ([]byte, error) {
+	return protocol.SignBytes(payload)
+}
+
+func nonceKey(nonce []byte) []byte {
+	return []byte(NoncePrefix + base64.StdEncoding.EncodeToString(nonce))
+}
+
+// verifyTx check chain ID, version, nonce and signature of a transaction,
+// it returns public keys of verified signers
+func (a *SitcomApplication) verifyTx(txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	payload := txObj.Payload
+	if !signedMethods[payload.Method] {
+		return nil, code.CodeTypeOK, ""
+	}
+
+	if payload.Version != SignVersion {
+		return nil, code.CodeTypeUnsupportedVersion, fmt.Sprintf("unsupported sign version %d, expected %d (sign over chain_id, method, nonce and params)", payload.Version, SignVersion)
+	}
+
+	if payload.ChainId != a.state.ChainID {
+		return nil, code.CodeTypeInvalidChainID, fmt.Sprintf("invalid chain id: %s", payload.ChainId)
+	}
+
+	if len(payload.Nonce) == 0 {
+		return nil, code.CodeTypeBadNonce, "nonce cannot be empty"
+	}
+
+	if a.state.db.Has(nonceKey(payload.Nonce)) {
+		return nil, code.CodeTypeDuplicateNonce, "nonce has already been used"
+	}
+
+	if payload.Method == "AddNewService" && !a.state.db.Has(adminKey) {
+		return a.checkBootstrap(payload)
+	}
+
+	signBytes, err := SignBytes(payload)
+	if err != nil {
+		return nil, code.CodeTypeEncodingError, "error when encoding sign bytes"
+	}
+
+	signers, resCode, resLog := a.checkSignatures(payload, signBytes, txObj)
+	if resCode != code.CodeTypeOK {
+		return nil, resCode, resLog
+	}
+
+	if resCode, resLog = a.checkRevokedSigners(signers); resCode != code.CodeTypeOK {
+		return nil, resCode, resLog
+	}
+
+	return signers, code.CodeTypeOK, ""
+}
+
+// checkSignatures verify signatures required by a sign policy, a role or sitcompetence
+func (a *SitcomApplication) checkSignatures(payload *protoTm.Payload, signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	switch payload.Method {
+	case "GiveBadgeBatch":
+		return a.checkBatchSignature(payload, signBytes, txObj)
+	case MultiMessageMethod:
+		return a.checkMessagesSignature(payload, signBytes, txObj)
+	case "ApproveProposal":
+		return a.checkApproveProposalSignature(payload, signBytes, txObj)
+	}
+
+	return a.checkMethodSignature(payload, signBytes, txObj)
+}
+
+// checkMethodSignature verify signatures required for the method of a payload
+func (a *SitcomApplication) checkMethodSignature(payload *protoTm.Payload, signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	policy, err := a.signPolicy(payload)
+	if err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal sign policy"
+	}
+
+	if policy != nil {
+		account, err := a.getMultisig(policy.Account)
+		if err != nil {
+			return nil, code.CodeTypeUnauthorized, err.Error()
+		}
+		return a.checkMultisig(account, signBytes, txObj.Signatures)
+	}
+
+	if payload.Method == "RotateKey" {
+		return a.checkRotateKeySignature(payload, signBytes, txObj)
+	}
+
+	if payload.Method == "EnrollActivity" {
+		return a.checkEnrollSignature(payload, signBytes, txObj.Signatures)
+	}
+
+	if activityMethods[payload.Method] {
+		return a.checkActivitySignature(payload, signBytes, txObj.Signatures)
+	}
+
+	if role, exists := methodRoles[payload.Method]; exists {
+		return a.checkRoleSignature(role, signBytes, txObj.Signatures)
+	}
+
+	return a.checkAdminSignature(signBytes, txObj)
+}
+
+// checkAdminSignature verify that the sitcompetence key signed the transaction,
+// either in the signature field or as one of the signatures
+func (a *SitcomApplication) checkAdminSignature(signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	b64PubKey := a.state.db.Get(adminKey)
+	if b64PubKey == nil {
+		return nil, code.CodeTypeUnauthorized, "sitcompetence publickey not found"
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(string(b64PubKey))
+	if err != nil {
+		return nil, code.CodeTypeDecodingError, "decoding error"
+	}
+
+	if verifySignature(KeyTypeEd25519, publicKey, signBytes, txObj.Signature) {
+		return [][]byte{publicKey}, code.CodeTypeOK, ""
+	}
+
+	for _, sig := range txObj.Signatures {
+		if bytes.Equal(sig.PublicKey, publicKey) && verifySignature(sig.KeyType, publicKey, signBytes, sig.Signature) {
+			return [][]byte{publicKey}, code.CodeTypeOK, ""
+		}
+	}
+
+	return nil, code.CodeTypeUnauthorized, "failed in signature verification"
+}
+
+// checkRoleSignature verify that a key holding the role signed the transaction
+func (a *SitcomApplication) checkRoleSignature(role string, signBytes []byte, signatures []*protoTm.Signature) ([][]byte, uint32, string) {
+	for _, sig := range signatures {
+		if !a.hasRole(role, sig.PublicKey) || a.isRevoked(sig.PublicKey) {
+			continue
+		}
+		if verifySignature(sig.KeyType, sig.PublicKey, signBytes, sig.Signature) {
+			return [][]byte{sig.PublicKey}, code.CodeTypeOK, ""
+		}
+	}
+
+	return nil, code.CodeTypeUnauthorized, fmt.Sprintf("requires a signature from %s", role)
+}
+
+// checkBootstrap allow the first sitcompetence key to be set without a
+// signature, as no key can sign before it exists
+func (a *SitcomApplication) checkBootstrap(payload *protoTm.Payload) ([][]byte, uint32, string) {
+	name, _, err := parseService(payload.Params)
+	if err != nil {
+		return nil, code.CodeTypeInvalidMethod, err.Error()
+	}
+
+	if !bytes.Equal(name, adminKey) {
+		return nil, code.CodeTypeUnauthorized, "sitcompetence publickey not found"
+	}
+
+	return nil, code.CodeTypeOK, ""
+}
+
+// useNonce mark a nonce of signed transaction as used
+func (a *SitcomApplication) useNonce(payload *protoTm.Payload) {
+	if signedMethods[payload.Method] {
+		a.state.db.Set(nonceKey(payload.Nonce), []byte{})
+	}
+}
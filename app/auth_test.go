@@ -0,0 +1,100 @@
+package app
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+func TestVerifyTx(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	_, other := ta.newKey()
+
+	tests := []struct {
+		name     string
+		keys     []ed25519.PrivateKey
+		before   func(payload *protoTm.Payload)
+		after    func(payload *protoTm.Payload)
+		expected uint32
+	}{
+		{"valid", []ed25519.PrivateKey{admin}, nil, nil, code.CodeTypeOK},
+		{"wrong chain id", []ed25519.PrivateKey{admin}, func(payload *protoTm.Payload) { payload.ChainId = "another-chain" }, nil, code.CodeTypeInvalidChainID},
+		{"old version", []ed25519.PrivateKey{admin}, func(payload *protoTm.Payload) { payload.Version = 0 }, nil, code.CodeTypeUnsupportedVersion},
+		{"empty nonce", []ed25519.PrivateKey{admin}, func(payload *protoTm.Payload) { payload.Nonce = nil }, nil, code.CodeTypeBadNonce},
+		{"tampered method", []ed25519.PrivateKey{admin}, nil, func(payload *protoTm.Payload) { payload.Method = "UpdateCompetence" }, code.CodeTypeUnauthorized},
+		{"tampered params", []ed25519.PrivateKey{admin}, nil, func(payload *protoTm.Payload) { payload.Params = []byte(`{"id":3,"name":"Leadership"}`) }, code.CodeTypeUnauthorized},
+		{"tampered nonce", []ed25519.PrivateKey{admin}, nil, func(payload *protoTm.Payload) { payload.Nonce = []byte("another nonce") }, code.CodeTypeUnauthorized},
+		{"unsigned", nil, nil, nil, code.CodeTypeUnauthorized},
+		{"another key", []ed25519.PrivateKey{other}, nil, nil, code.CodeTypeUnauthorized},
+	}
+
+	for i, test := range tests {
+		payload := ta.payload("CreateCompetence", Competence{ID: uint32(i + 1), Name: "Teamwork"})
+		if test.before != nil {
+			test.before(payload)
+		}
+
+		signatures := ta.sign(payload, test.keys...)
+		if test.after != nil {
+			test.after(payload)
+		}
+
+		if res := ta.deliverTx(ta.encode(&protoTm.Tx{Payload: payload, Signatures: signatures})); res.Code != test.expected {
+			t.Fatalf("%s: expected code %d, got %d: %s", test.name, test.expected, res.Code, res.Log)
+		}
+	}
+}
+
+func TestNonceReplay(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+
+	tx := ta.tx("CreateCompetence", Competence{ID: 1, Name: "Teamwork"}, admin)
+	if res := ta.deliverTx(tx); res.Code != code.CodeTypeOK {
+		t.Fatalf("expected code %d, got %d: %s", code.CodeTypeOK, res.Code, res.Log)
+	}
+	if res := ta.deliverTx(tx); res.Code != code.CodeTypeDuplicateNonce {
+		t.Fatalf("replayed transaction: expected code %d, got %d: %s", code.CodeTypeDuplicateNonce, res.Code, res.Log)
+	}
+
+	// the nonce of a transaction is used even with other params
+	payload := ta.payload("CreateCompetence", Competence{ID: 2, Name: "Leadership"})
+	payload.Nonce = []byte("nonce-1")
+	if res := ta.deliverTx(ta.encode(&protoTm.Tx{Payload: payload, Signatures: ta.sign(payload, admin)})); res.Code != code.CodeTypeDuplicateNonce {
+		t.Fatalf("reused nonce: expected code %d, got %d: %s", code.CodeTypeDuplicateNonce, res.Code, res.Log)
+	}
+}
+
+func TestServiceMethodsNeedSignature(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	publicKey, _ := ta.newKey()
+	service := "sitcompetence:" + base64.StdEncoding.EncodeToString(publicKey)
+
+	// only the sitcompetence key can be set before a key exists
+	ta.must(code.CodeTypeUnauthorized, "AddNewService", "registrar:registrar.example.com")
+	ta.must(code.CodeTypeUnauthorized, "SetValidator", SetValidatorParam{PublicKey: publicKey, Power: 10})
+
+	admin := ta.admin()
+	ta.must(code.CodeTypeUnauthorized, "AddNewService", service)
+	ta.must(code.CodeTypeDuplicateKey, "AddNewService", service, admin)
+
+	ta.must(code.CodeTypeUnauthorized, "AddNewService", "registrar:registrar.example.com")
+	ta.must(code.CodeTypeOK, "AddNewService", "registrar:registrar.example.com", admin)
+	if value := ta.app.state.db.Get([]byte(ServicePrefix + "registrar")); string(value) != "registrar.example.com" {
+		t.Fatalf("expected the service under %s, got %q", ServicePrefix, value)
+	}
+
+	ta.must(code.CodeTypeUnauthorized, "SetValidator", SetValidatorParam{PublicKey: publicKey, Power: 10})
+	ta.must(code.CodeTypeOK, "SetValidator", SetValidatorParam{PublicKey: publicKey, Power: 10}, admin)
+}
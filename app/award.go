@@ -0,0 +1,192 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// AwardRulePrefix define the prefix of tag to competence index of award rules
+	AwardRulePrefix string = "awardrule:"
+
+	// AwardCountPrefix define the prefix of approved activity counters
+	AwardCountPrefix string = "awardcount:"
+)
+
+func awardRulePrefix(tag string) []byte {
+	return []byte(AwardRulePrefix + tag + ":")
+}
+
+func awardRuleKey(tag string, competenceID uint32) []byte {
+	return append(awardRulePrefix(tag), strconv.FormatUint(uint64(competenceID), 10)...)
+}
+
+func awardCountKey(semester uint32, tag string, studentID string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s:%s", AwardCountPrefix, semester, tag, studentID))
+}
+
+func validateAwardRule(rule *AwardRule) error {
+	if rule == nil {
+		return nil
+	}
+
+	if rule.Tag == "" || rule.Count == 0 {
+		return errors.New("award rule needs a tag and a count")
+	}
+
+	if strings.Contains(rule.Tag, ":") {
+		return errors.New("award rule tag cannot contain ':'")
+	}
+
+	return nil
+}
+
+// indexAwardRule replace the index entry of a competence's award rule
+func (a *SitcomApplication) indexAwardRule(old, new *Competence) {
+	if old != nil && old.AwardRule != nil {
+		a.state.db.Delete(awardRuleKey(old.AwardRule.Tag, old.ID))
+	}
+
+	if new != nil && new.AwardRule != nil {
+		a.state.db.Set(awardRuleKey(new.AwardRule.Tag, new.ID), []byte{})
+	}
+}
+
+// countApproval increase the student's number of approved activities with the tag
+func (a *SitcomApplication) countApproval(semester uint32, tag string, studentID string) uint64 {
+	return a.incrCounter(awardCountKey(semester, tag, studentID))
+}
+
+// awardPolicySigners return the signers a sign policy requires for GiveBadge
+// of the competence, false when the approving transaction lacks them. The
+// award rule set by sitcompetence stands in for its own signature, but not
+// for a multisig policy.
+func (a *SitcomApplication) awardPolicySigners(competenceID uint32, txObj *protoTm.Tx) ([][]byte, bool) {
+	params, err := json.Marshal(GiveBadge{CompetenceID: competenceID})
+	if err != nil {
+		return nil, false
+	}
+
+	policy, err := a.signPolicy(&protoTm.Payload{Method: "GiveBadge", Params: params})
+	if err != nil {
+		return nil, false
+	}
+	if policy == nil {
+		return nil, true
+	}
+
+	account, err := a.getMultisig(policy.Account)
+	if err != nil {
+		return nil, false
+	}
+
+	signBytes, err := SignBytes(txObj.Payload)
+	if err != nil {
+		return nil, false
+	}
+
+	signers, resCode, _ := a.checkMultisig(account, signBytes, txObj.Signatures)
+	return signers, resCode == code.CodeTypeOK
+}
+
+// awardBadges count a new activity approval and give every competence whose
+// award rule is now met, with the portfolio leaf the approval carries for
+// the competence, it returns an award event for each given badge
+func (a *SitcomApplication) awardBadges(approval *ApproveActivity, txObj *protoTm.Tx, signers [][]byte) ([]types.Event, error) {
+	activityID, studentID := approval.ActivityID, approval.StudentID
+	activity, err := a.getActivity(activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]types.Event, 0)
+	for _, tag := range activity.Tags {
+		count := a.countApproval(activity.Semester, tag, studentID)
+
+		competenceIDs := make([]uint32, 0)
+		prefix := awardRulePrefix(tag)
+		itr := dbm.IteratePrefix(a.state.db, prefix)
+		for ; itr.Valid(); itr.Next() {
+			id, err := strconv.ParseUint(string(bytes.TrimPrefix(itr.Key(), prefix)), 10, 32)
+			if err == nil {
+				competenceIDs = append(competenceIDs, uint32(id))
+			}
+		}
+		itr.Close()
+
+		for _, competenceID := range competenceIDs {
+			competence, err := a.getCompetence(competenceID)
+			if err != nil || competence.Deprecated || competence.AwardRule == nil || count < uint64(competence.AwardRule.Count) {
+				continue
+			}
+
+			if len(a.missingPrerequisites(studentID, competence)) > 0 {
+				continue
+			}
+
+			policySigners, ok := a.awardPolicySigners(competenceID, txObj)
+			if !ok {
+				continue
+			}
+
+			if resCode, _ := a.checkSemesterOpen(activity.Semester, false); resCode != code.CodeTypeOK {
+				continue
+			}
+
+			badge := GiveBadge{
+				StudentID:     studentID,
+				CompetenceID:  competenceID,
+				Semester:      activity.Semester,
+				PortfolioLeaf: approval.PortfolioLeaves[competenceID],
+			}
+			badgeKey, err := BadgeKey(&badge)
+			if err != nil {
+				return nil, err
+			}
+
+			// already given, keep the first issuance
+			if a.state.db.Has(badgeKey) {
+				continue
+			}
+
+			badgeParams, err := json.Marshal(badge)
+			if err != nil {
+				return nil, err
+			}
+
+			badgeSigners := append([][]byte{}, signers...)
+			for _, signer := range policySigners {
+				if !containsKey(badgeSigners, signer) {
+					badgeSigners = append(badgeSigners, signer)
+				}
+			}
+
+			if res, err := a.giveBadge(badgeParams, txObj, badgeSigners); err != nil {
+				return nil, fmt.Errorf("award competence %d: %s", competenceID, res.Log)
+			}
+
+			events = append(events, types.Event{
+				Type: "award",
+				Attributes: []cmn.KVPair{
+					{Key: []byte("student_id"), Value: []byte(studentID)},
+					{Key: []byte("competence_id"), Value: []byte(strconv.FormatUint(uint64(competenceID), 10))},
+					{Key: []byte("semester"), Value: []byte(strconv.FormatUint(uint64(activity.Semester), 10))},
+					{Key: []byte("activity_id"), Value: []byte(strconv.FormatUint(uint64(activityID), 10))},
+				},
+			})
+		}
+	}
+
+	return events, nil
+}
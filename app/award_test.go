@@ -0,0 +1,97 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestAutoAward(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork", AwardRule: &AwardRule{Tag: "volunteer", Count: 2}})
+	ta.must(code.CodeTypeInvalidParams, "CreateCompetence", Competence{ID: 2, Name: "Leadership", AwardRule: &AwardRule{Tag: "volunteer"}}, admin)
+	ta.must(code.CodeTypeInvalidParams, "CreateCompetence", Competence{ID: 2, Name: "Leadership", AwardRule: &AwardRule{Tag: "a:b", Count: 1}}, admin)
+
+	for _, activity := range []Activity{
+		{ID: 1, Title: "Beach cleanup", Tags: []string{"volunteer"}, Semester: 1},
+		{ID: 2, Title: "Blood drive", Tags: []string{"volunteer"}, Semester: 1},
+		{ID: 3, Title: "Hackathon", Tags: []string{"technical"}, Semester: 1},
+		{ID: 4, Title: "Food bank", Tags: []string{"volunteer"}, Semester: 1},
+	} {
+		ta.must(code.CodeTypeOK, "CreateActivity", activity, organizer)
+		ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: activity.ID, State: ActivityOpen}, organizer)
+	}
+
+	studentID := ta.student("1")
+	badge := GiveBadge{StudentID: studentID, CompetenceID: 1, Semester: 1}
+
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: studentID, ActivityID: 1}, organizer)
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: studentID, ActivityID: 3}, organizer)
+	if ta.hasBadge(badge) {
+		t.Fatal("badge awarded before the rule's count")
+	}
+
+	res := ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: studentID, ActivityID: 2}, organizer)
+	if !ta.hasBadge(badge) {
+		t.Fatal("badge was not awarded")
+	}
+
+	awarded := false
+	for _, event := range res.Events {
+		if event.Type == "award" {
+			for _, attribute := range event.Attributes {
+				if bytes.Equal(attribute.Key, []byte("competence_id")) && bytes.Equal(attribute.Value, []byte("1")) {
+					awarded = true
+				}
+			}
+		}
+	}
+	if !awarded {
+		t.Fatalf("expected an award event, got %+v", res.Events)
+	}
+
+	// an award already given keeps its first issuance
+	first := ta.verifyBadge(badge)
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: studentID, ActivityID: 4}, organizer)
+	if again := ta.verifyBadge(badge); again.TxHash != first.TxHash {
+		t.Fatalf("award was issued again by %s", again.TxHash)
+	}
+}
+
+func TestAutoAwardRequiresSignPolicy(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork", AwardRule: &AwardRule{Tag: "volunteer", Count: 1}})
+	advisorKey, advisor := ta.newKey()
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "advisors", Threshold: 1, PublicKeys: [][]byte{advisorKey}}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "GiveBadge", CompetenceID: 1, Account: "advisors"}, admin)
+
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Beach cleanup", Tags: []string{"volunteer"}, Semester: 1}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	// the organizer alone cannot award a competence the policy gives to advisors
+	unsigned := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: unsigned.StudentID, ActivityID: 1}, organizer)
+	if ta.hasBadge(unsigned) {
+		t.Fatal("badge awarded without the signature of its sign policy")
+	}
+
+	signed := GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: signed.StudentID, ActivityID: 1}, organizer, advisor)
+	if !ta.hasBadge(signed) {
+		t.Fatal("badge was not awarded with the signature of its sign policy")
+	}
+
+	verification := ta.verifyBadge(signed)
+	found := false
+	for _, signer := range verification.Signers {
+		found = found || bytes.Equal(signer.PublicKey, advisorKey)
+	}
+	if !verification.Valid || !found {
+		t.Fatalf("expected a valid award signed by the advisor, got %+v", verification)
+	}
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestDuplicateBadgeKeepsFirstIssuance(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	ta.block(1)
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+	advisorKey, advisor := ta.newKey()
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: advisorKey, Role: RoleAdvisor}, admin)
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 2, Name: "2/2019", Start: 0, End: 1 << 40}, admin)
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1, Narrative: "led the team"}
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+	first := ta.verifyBadge(badge)
+
+	// the key ignores other params, so another narrative is the same badge
+	again := badge
+	again.Narrative = "another narrative"
+	ta.block(2)
+	ta.must(code.CodeTypeDuplicateKey, "GiveBadge", again, admin)
+	ta.must(code.CodeTypeDuplicateKey, "ProposeBadge", again, advisor)
+	ta.must(code.CodeTypeDuplicateKey, "GiveBadgeBatch", GiveBadgeBatch{Entries: []GiveBadge{again}}, admin)
+
+	key, err := BadgeKey(&badge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored GiveBadge
+	if err := json.Unmarshal(ta.app.state.db.Get(key), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Narrative != badge.Narrative {
+		t.Fatalf("expected the first narrative, got %q", stored.Narrative)
+	}
+	if latest := ta.verifyBadge(badge); latest.TxHash != first.TxHash || latest.Height != first.Height {
+		t.Fatalf("expected the first issuance %s, got %s", first.TxHash, latest.TxHash)
+	}
+
+	// the same competence in another semester is another badge
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: badge.StudentID, CompetenceID: 1, Semester: 2}, admin)
+}
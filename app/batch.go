@@ -0,0 +1,115 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+// MaxBatchEntries is the maximum number of entries in a GiveBadgeBatch
+const MaxBatchEntries = 500
+
+// checkBatchSignature verify that a batch carries the signatures GiveBadge
+// requires for every competence of its entries, so a batch cannot bypass
+// a per-competence sign policy
+func (a *SitcomApplication) checkBatchSignature(payload *protoTm.Payload, signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	var batch GiveBadgeBatch
+	if err := json.Unmarshal(payload.Params, &batch); err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal params"
+	}
+
+	if len(batch.Entries) == 0 || len(batch.Entries) > MaxBatchEntries {
+		return nil, code.CodeTypeInvalidParams, fmt.Sprintf("a batch needs 1 to %d entries", MaxBatchEntries)
+	}
+
+	signers := make([][]byte, 0)
+	checked := make(map[uint32]bool)
+	seen := make(map[string]bool)
+	for _, entry := range batch.Entries {
+		if checked[entry.CompetenceID] {
+			continue
+		}
+		checked[entry.CompetenceID] = true
+
+		params, err := json.Marshal(GiveBadge{CompetenceID: entry.CompetenceID})
+		if err != nil {
+			return nil, code.CodeTypeEncodingError, "error when marshal params"
+		}
+
+		entrySigners, resCode, resLog := a.checkSignatures(&protoTm.Payload{Method: "GiveBadge", Params: params}, signBytes, txObj)
+		if resCode != code.CodeTypeOK {
+			return nil, resCode, fmt.Sprintf("competence %d: %s", entry.CompetenceID, resLog)
+		}
+
+		for _, signer := range entrySigners {
+			if !seen[string(signer)] {
+				seen[string(signer)] = true
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	return signers, code.CodeTypeOK, ""
+}
+
+// giveBadgeBatch give every badge of a batch or none of them,
+// the response data lists the result of each entry
+func (a *SitcomApplication) giveBadgeBatch(payload []byte, txObj *protoTm.Tx, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var batch GiveBadgeBatch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	results := make([]BatchResult, 0, len(batch.Entries))
+	err = a.atomic(func() error {
+		for i, entry := range batch.Entries {
+			params, err := json.Marshal(entry)
+			if err != nil {
+				res.Code = code.CodeTypeEncodingError
+				res.Log = fmt.Sprintf("entry %d: error when marshal entry", i)
+				results = append(results, BatchResult{Index: i, Code: res.Code, Log: res.Log})
+				return err
+			}
+
+			var entryRes types.ResponseDeliverTx
+			a.withLocation(a.itemLocation(i), func() {
+				entryRes, err = a.giveBadge(params, txObj, signers)
+			})
+			results = append(results, BatchResult{Index: i, Code: entryRes.Code, Log: entryRes.Log})
+			if err != nil {
+				res.Code = entryRes.Code
+				res.Log = fmt.Sprintf("entry %d: %s", i, entryRes.Log)
+				return errors.New(res.Log)
+			}
+		}
+
+		return nil
+	})
+
+	data, marshalErr := json.Marshal(results)
+	if marshalErr != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal results"
+		a.logger.Infoln(res.Log)
+		return res, marshalErr
+	}
+	res.Data = data
+
+	if err != nil {
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestBatchRollsBackOnFailedEntry(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	first := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	second := GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}
+	batch := GiveBadgeBatch{Entries: []GiveBadge{first, second, first}}
+
+	res := ta.must(code.CodeTypeDuplicateKey, "GiveBadgeBatch", batch, admin)
+
+	var results []BatchResult
+	if err := json.Unmarshal(res.Data, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[2].Code != code.CodeTypeDuplicateKey {
+		t.Fatalf("expected entry 2 to fail as a duplicate, got %+v", results)
+	}
+
+	if ta.hasBadge(first) || ta.hasBadge(second) {
+		t.Fatal("badges of a failed batch are kept")
+	}
+	if stats := ta.app.semesterStats(1); stats.Badges != 0 {
+		t.Fatalf("badges of a failed batch are counted: %+v", stats)
+	}
+	if badgeKeys, err := ta.app.getPortfolio(first.StudentID); err != nil || len(badgeKeys) != 0 {
+		t.Fatalf("portfolio of a failed batch is kept: %v %v", badgeKeys, err)
+	}
+
+	ta.must(code.CodeTypeOK, "GiveBadgeBatch", GiveBadgeBatch{Entries: []GiveBadge{first, second}}, admin)
+	if !ta.hasBadge(first) || !ta.hasBadge(second) {
+		t.Fatal("badges of a batch not given")
+	}
+}
+
+func TestBatchRequiresEverySignPolicy(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+
+	memberKey, member := ta.newKey()
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "leaders", Threshold: 1, PublicKeys: [][]byte{memberKey}}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "GiveBadge", CompetenceID: 2, Account: "leaders"}, admin)
+
+	studentID := ta.student("1")
+	batch := GiveBadgeBatch{Entries: []GiveBadge{
+		{StudentID: studentID, CompetenceID: 1, Semester: 1},
+		{StudentID: studentID, CompetenceID: 2, Semester: 1},
+	}}
+
+	ta.must(code.CodeTypeUnauthorized, "GiveBadgeBatch", batch, admin)
+	ta.must(code.CodeTypeOK, "GiveBadgeBatch", batch, admin, member)
+}
+
+func TestBatchKeepsTransactionOnce(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	entries := make([]GiveBadge, 0, 20)
+	for i := 0; i < cap(entries); i++ {
+		entries = append(entries, GiveBadge{StudentID: ta.student(fmt.Sprint(i)), CompetenceID: 1, Semester: 1})
+	}
+	ta.must(code.CodeTypeOK, "GiveBadgeBatch", GiveBadgeBatch{Entries: entries}, admin)
+
+	if count := ta.countPrefix([]byte(IssuanceTxPrefix)); count != 1 {
+		t.Fatalf("expected the batch kept once, got %d transactions", count)
+	}
+
+	txHash := ta.verifyBadge(entries[0]).TxHash
+	for _, entry := range entries {
+		verification := ta.verifyBadge(entry)
+		if !verification.Valid || verification.TxHash != txHash {
+			t.Fatalf("expected a valid badge of transaction %s, got %+v", txHash, verification)
+		}
+	}
+}
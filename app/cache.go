@@ -0,0 +1,268 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// cacheDB buffer writes over a parent DB, so changes of a group of
+// operations can be written to the parent or discarded together
+type cacheDB struct {
+	parent dbm.DB
+	writes map[string]*cacheValue
+}
+
+type cacheValue struct {
+	value   []byte
+	deleted bool
+}
+
+var _ dbm.DB = (*cacheDB)(nil)
+
+func newCacheDB(parent dbm.DB) *cacheDB {
+	return &cacheDB{
+		parent: parent,
+		writes: make(map[string]*cacheValue),
+	}
+}
+
+func (db *cacheDB) Get(key []byte) []byte {
+	if cached, exists := db.writes[string(key)]; exists {
+		if cached.deleted {
+			return nil
+		}
+		return cached.value
+	}
+
+	return db.parent.Get(key)
+}
+
+func (db *cacheDB) Has(key []byte) bool {
+	if cached, exists := db.writes[string(key)]; exists {
+		return !cached.deleted
+	}
+
+	return db.parent.Has(key)
+}
+
+func (db *cacheDB) Set(key []byte, value []byte) {
+	db.writes[string(key)] = &cacheValue{value: append([]byte{}, value...)}
+}
+
+func (db *cacheDB) SetSync(key []byte, value []byte) {
+	db.Set(key, value)
+}
+
+func (db *cacheDB) Delete(key []byte) {
+	db.writes[string(key)] = &cacheValue{deleted: true}
+}
+
+func (db *cacheDB) DeleteSync(key []byte) {
+	db.Delete(key)
+}
+
+func (db *cacheDB) Iterator(start, end []byte) dbm.Iterator {
+	return db.newIterator(start, end, false)
+}
+
+func (db *cacheDB) ReverseIterator(start, end []byte) dbm.Iterator {
+	return db.newIterator(start, end, true)
+}
+
+// newIterator merge the parent's keys in the domain with buffered writes
+func (db *cacheDB) newIterator(start, end []byte, reverse bool) dbm.Iterator {
+	merged := make(map[string][]byte)
+
+	itr := db.parent.Iterator(start, end)
+	for ; itr.Valid(); itr.Next() {
+		merged[string(itr.Key())] = itr.Value()
+	}
+	itr.Close()
+
+	for key, cached := range db.writes {
+		if !dbm.IsKeyInDomain([]byte(key), start, end) {
+			continue
+		}
+		if cached.deleted {
+			delete(merged, key)
+		} else {
+			merged[key] = cached.value
+		}
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	return &cacheIterator{start: start, end: end, keys: keys, values: merged}
+}
+
+func (db *cacheDB) Close() {}
+
+func (db *cacheDB) NewBatch() dbm.Batch {
+	return &cacheBatch{db: db}
+}
+
+func (db *cacheDB) Print() {
+	for key, cached := range db.writes {
+		fmt.Printf("[%X]:\t[%X]\t(deleted: %v)\n", []byte(key), cached.value, cached.deleted)
+	}
+}
+
+func (db *cacheDB) Stats() map[string]string {
+	return map[string]string{
+		"database.type": "cacheDB",
+		"database.size": fmt.Sprintf("%d", len(db.writes)),
+	}
+}
+
+// write apply buffered writes to the parent in one batch
+func (db *cacheDB) write() {
+	keys := make([]string, 0, len(db.writes))
+	for key := range db.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	batch := db.parent.NewBatch()
+	defer batch.Close()
+	for _, key := range keys {
+		if cached := db.writes[key]; cached.deleted {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Set([]byte(key), cached.value)
+		}
+	}
+	batch.Write()
+
+	db.writes = make(map[string]*cacheValue)
+}
+
+type cacheIterator struct {
+	start  []byte
+	end    []byte
+	keys   []string
+	values map[string][]byte
+}
+
+func (itr *cacheIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+func (itr *cacheIterator) Valid() bool {
+	return len(itr.keys) > 0
+}
+
+func (itr *cacheIterator) Next() {
+	itr.assertValid()
+	itr.keys = itr.keys[1:]
+}
+
+func (itr *cacheIterator) Key() []byte {
+	itr.assertValid()
+	return []byte(itr.keys[0])
+}
+
+func (itr *cacheIterator) Value() []byte {
+	itr.assertValid()
+	return itr.values[itr.keys[0]]
+}
+
+func (itr *cacheIterator) Close() {}
+
+func (itr *cacheIterator) assertValid() {
+	if !itr.Valid() {
+		panic("cacheIterator is invalid")
+	}
+}
+
+type cacheBatch struct {
+	db  *cacheDB
+	ops []func()
+}
+
+func (batch *cacheBatch) Set(key, value []byte) {
+	key, value = append([]byte{}, key...), append([]byte{}, value...)
+	batch.ops = append(batch.ops, func() { batch.db.Set(key, value) })
+}
+
+func (batch *cacheBatch) Delete(key []byte) {
+	key = append([]byte{}, key...)
+	batch.ops = append(batch.ops, func() { batch.db.Delete(key) })
+}
+
+func (batch *cacheBatch) Write() {
+	for _, op := range batch.ops {
+		op()
+	}
+	batch.ops = nil
+}
+
+func (batch *cacheBatch) WriteSync() {
+	batch.Write()
+}
+
+func (batch *cacheBatch) Close() {}
+
+// atomic run fn over a cache of the state, its writes are kept only when it
+// succeeds and are discarded when it fails or panics. Validator updates are
+// rolled back with them and off-chain effects registered with afterWrite
+// run only once the outermost atomic call is written.
+func (a *SitcomApplication) atomic(fn func() error) error {
+	parent, size := a.state.db, a.state.Size
+	valUpdates := make(map[string]types.ValidatorUpdate, len(a.valUpdates))
+	for key, update := range a.valUpdates {
+		valUpdates[key] = update
+	}
+	pending, effects := a.pending, make([]func(), 0)
+	cache := newCacheDB(parent)
+
+	a.state.db = cache
+	a.pending = &effects
+	committed := false
+	defer func() {
+		a.state.db = parent
+		a.pending = pending
+		if !committed {
+			a.state.Size = size
+			a.valUpdates = valUpdates
+		}
+	}()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	cache.write()
+	committed = true
+
+	if pending != nil {
+		*pending = append(*pending, effects...)
+		return nil
+	}
+	for _, effect := range effects {
+		effect()
+	}
+	return nil
+}
+
+// afterWrite run an off-chain effect of a transaction once its state is
+// written, immediately unless it runs inside atomic
+func (a *SitcomApplication) afterWrite(effect func()) {
+	if a.pending != nil {
+		*a.pending = append(*a.pending, effect)
+		return
+	}
+
+	effect()
+}
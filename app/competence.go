@@ -0,0 +1,303 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// CompetencePrefix define the prefix of competence catalog keys
+	CompetencePrefix string = "competence:"
+)
+
+func competenceKey(id uint32) []byte {
+	return []byte(CompetencePrefix + strconv.FormatUint(uint64(id), 10))
+}
+
+func (a *SitcomApplication) getCompetence(id uint32) (*Competence, error) {
+	value := a.state.db.Get(competenceKey(id))
+	if value == nil {
+		return nil, fmt.Errorf("competence %d not found", id)
+	}
+
+	var competence Competence
+	if err := json.Unmarshal(value, &competence); err != nil {
+		return nil, err
+	}
+
+	return &competence, nil
+}
+
+func (a *SitcomApplication) setCompetence(competence *Competence) error {
+	value, err := json.Marshal(competence)
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(competenceKey(competence.ID), value)
+	return nil
+}
+
+// validateMetadata check the badge metadata of a competence,
+// the image hash is a sha256 in hex of the badge image
+func validateMetadata(competence *Competence) error {
+	if competence.ImageHash == "" {
+		return nil
+	}
+
+	if hashed, err := hex.DecodeString(competence.ImageHash); err != nil || len(hashed) != sha256.Size {
+		return fmt.Errorf("image_hash must be a sha256 in hex")
+	}
+
+	return nil
+}
+
+// checkCompetence return an error code when a badge cannot be given for the competence
+func (a *SitcomApplication) checkCompetence(id uint32) (uint32, string) {
+	competence, err := a.getCompetence(id)
+	if err != nil {
+		return code.CodeTypeNotFound, err.Error()
+	}
+
+	if competence.Deprecated {
+		return code.CodeTypeInvalidParams, fmt.Sprintf("competence %d is deprecated", id)
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+func (a *SitcomApplication) createCompetence(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var competence Competence
+	if err := json.Unmarshal(payload, &competence); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if competence.ID == 0 || competence.Name == "" {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "competence needs an id and a name"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if a.state.db.Has(competenceKey(competence.ID)) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = fmt.Sprintf("competence %d already exists", competence.ID)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if err := validateMetadata(&competence); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := validateAwardRule(competence.AwardRule); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := a.validatePrerequisites(competence.ID, competence.Prerequisites); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if competence.Prerequisites == nil {
+		competence.Prerequisites = make([]uint32, 0)
+	}
+	competence.Deprecated = false
+	competence.CreatedHeight = a.state.Height
+	competence.UpdatedHeight = a.state.Height
+	if err := a.setCompetence(&competence); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal competence"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+	a.indexAwardRule(nil, &competence)
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) updateCompetence(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var update Competence
+	if err := json.Unmarshal(payload, &update); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	competence, err := a.getCompetence(update.ID)
+	if err != nil {
+		res.Code = code.CodeTypeNotFound
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if update.Name == "" {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "name cannot be empty"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if err := validateMetadata(&update); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := validateAwardRule(update.AwardRule); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := a.validatePrerequisites(competence.ID, update.Prerequisites); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if update.Prerequisites == nil {
+		update.Prerequisites = make([]uint32, 0)
+	}
+	a.indexAwardRule(competence, &update)
+	competence.Prerequisites = update.Prerequisites
+	competence.AwardRule = update.AwardRule
+	competence.Name = update.Name
+	competence.Description = update.Description
+	competence.Level = update.Level
+	competence.Category = update.Category
+	competence.Department = update.Department
+	competence.AchievementType = update.AchievementType
+	competence.Criteria = update.Criteria
+	competence.Image = update.Image
+	competence.ImageHash = update.ImageHash
+	competence.UpdatedHeight = a.state.Height
+	if err := a.setCompetence(competence); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal competence"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) deprecateCompetence(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var param DeprecateCompetenceParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	competence, err := a.getCompetence(param.ID)
+	if err != nil {
+		res.Code = code.CodeTypeNotFound
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	competence.Deprecated = true
+	competence.UpdatedHeight = a.state.Height
+	if err := a.setCompetence(competence); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal competence"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) queryCompetence(data []byte) (res types.ResponseQuery) {
+	id, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid competence id"
+		return
+	}
+
+	competence, err := a.getCompetence(uint32(id))
+	if err != nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = competenceKey(competence.ID)
+	res.Value, err = json.Marshal(competence)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal competence"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+// queryCompetences list the catalog, optionally only one category
+func (a *SitcomApplication) queryCompetences(category []byte) (res types.ResponseQuery) {
+	competences := make([]*Competence, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(CompetencePrefix))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var competence Competence
+		if err := json.Unmarshal(itr.Value(), &competence); err != nil {
+			continue
+		}
+
+		if len(category) == 0 || competence.Category == string(category) {
+			competences = append(competences, &competence)
+		}
+	}
+
+	value, err := json.Marshal(competences)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal competences"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = value
+	return
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestCompetenceCatalog(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	ta.block(1)
+	admin := ta.admin()
+	_, stranger := ta.newKey()
+
+	teamwork := Competence{ID: 1, Name: "Teamwork", Category: "soft skill", Level: 1}
+	ta.must(code.CodeTypeUnauthorized, "CreateCompetence", teamwork, stranger)
+	ta.must(code.CodeTypeOK, "CreateCompetence", teamwork, admin)
+	ta.must(code.CodeTypeDuplicateKey, "CreateCompetence", teamwork, admin)
+	ta.must(code.CodeTypeInvalidParams, "CreateCompetence", Competence{ID: 2}, admin)
+	ta.must(code.CodeTypeInvalidParams, "CreateCompetence", Competence{ID: 2, Name: "Golang", ImageHash: "not a hash"}, admin)
+	ta.must(code.CodeTypeOK, "CreateCompetence", Competence{ID: 2, Name: "Golang", Category: "technical", ImageHash: strings.Repeat("ab", 32)}, admin)
+
+	ta.block(2)
+	ta.must(code.CodeTypeNotFound, "UpdateCompetence", Competence{ID: 3, Name: "Leadership"}, admin)
+	ta.must(code.CodeTypeInvalidParams, "UpdateCompetence", Competence{ID: 1}, admin)
+	ta.must(code.CodeTypeOK, "UpdateCompetence", Competence{ID: 1, Name: "Teamwork and collaboration", Category: "soft skill"}, admin)
+
+	var competence Competence
+	ta.queryJSON("/competence", "1", &competence)
+	if competence.Name != "Teamwork and collaboration" || competence.CreatedHeight != 1 || competence.UpdatedHeight != 2 {
+		t.Fatalf("unexpected competence %+v", competence)
+	}
+	if res := ta.query("/competence", "3"); res.Log != "does not exist" {
+		t.Fatalf("expected no competence 3, got %s", res.Log)
+	}
+	if res := ta.query("/competence", "first"); res.Code != code.CodeTypeDecodingError {
+		t.Fatalf("expected code %d for an invalid id, got %d", code.CodeTypeDecodingError, res.Code)
+	}
+
+	var competences []Competence
+	ta.queryJSON("/competences", "", &competences)
+	if len(competences) != 2 {
+		t.Fatalf("expected 2 competences, got %+v", competences)
+	}
+	ta.queryJSON("/competences", "technical", &competences)
+	if len(competences) != 1 || competences[0].ID != 2 {
+		t.Fatalf("expected the technical competence, got %+v", competences)
+	}
+}
+
+func TestDeprecatedCompetence(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+
+	given := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", given, admin)
+	ta.must(code.CodeTypeNotFound, "GiveBadge", GiveBadge{StudentID: ta.student("1"), CompetenceID: 3, Semester: 1}, admin)
+
+	ta.must(code.CodeTypeNotFound, "DeprecateCompetence", DeprecateCompetenceParam{ID: 3}, admin)
+	ta.must(code.CodeTypeOK, "DeprecateCompetence", DeprecateCompetenceParam{ID: 1}, admin)
+	ta.must(code.CodeTypeInvalidParams, "GiveBadge", GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}, admin)
+
+	// badges given before the deprecation are kept
+	if !ta.hasBadge(given) {
+		t.Fatal("badge of a deprecated competence was removed")
+	}
+
+	var competence Competence
+	ta.queryJSON("/competence", "1", &competence)
+	if !competence.Deprecated {
+		t.Fatal("expected competence 1 deprecated")
+	}
+}
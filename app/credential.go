@@ -0,0 +1,201 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/credential"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/protocol"
+)
+
+// getCredential render a badge as a verifiable credential issued by the first
+// signer of the transaction which gave it, and return the signer's identity
+func (a *SitcomApplication) getCredential(badgeKey []byte) (*credential.Credential, *Identity, error) {
+	value := a.state.db.Get(badgeKey)
+	if value == nil {
+		return nil, nil, nil
+	}
+
+	var badge GiveBadge
+	if err := json.Unmarshal(value, &badge); err != nil {
+		return nil, nil, err
+	}
+
+	if !a.state.db.Has(issuanceKey(badgeKey)) {
+		return nil, nil, nil
+	}
+
+	issuance, txObj, err := a.getIssuance(badgeKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// only the badge's own payload is disclosed, with its path up to the
+	// transaction when the transaction gave other badges too
+	payload, path, err := protocol.Prove(txObj.Payload, issuance.Location)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := credential.Issuance{
+		ChainID:      txObj.Payload.ChainId,
+		StudentID:    badge.StudentID,
+		CompetenceID: badge.CompetenceID,
+		Semester:     badge.Semester,
+		Height:       issuance.Height,
+		Time:         issuance.Time,
+		TxHash:       issuance.TxHash,
+		Payload:      payload,
+		Path:         path,
+	}
+
+	var identity *Identity
+	if len(issuance.Signers) > 0 {
+		record.PublicKey = issuance.Signers[0]
+		signer, err := a.identityOf(record.PublicKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if signer != nil {
+			identity = signer
+			record.Identity = signer.ID
+		}
+		record.Signature, record.KeyType = signatureOf(txObj, record.PublicKey, identity)
+	}
+
+	vc, err := credential.New(&record)
+	return vc, identity, err
+}
+
+// signatureOf return the signature of a public key in a transaction and its key type
+func signatureOf(txObj *protoTm.Tx, publicKey []byte, identity *Identity) ([]byte, string) {
+	for _, sig := range txObj.Signatures {
+		if bytes.Equal(sig.PublicKey, publicKey) {
+			return sig.Signature, sig.KeyType
+		}
+	}
+
+	keyType := KeyTypeEd25519
+	if identity != nil {
+		keyType = identity.keyTypeOf(publicKey)
+	}
+	return txObj.Signature, keyType
+}
+
+// queryCredential return a badge as a W3C verifiable credential, data is the badge key
+func (a *SitcomApplication) queryCredential(data []byte) (res types.ResponseQuery) {
+	vc, _, err := a.getCredential(data)
+	if err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal badge"
+		return
+	}
+
+	if vc == nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = data
+	res.Value, err = json.Marshal(vc)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal credential"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+// getOpenBadge render a badge as an Open Badges 3.0 assertion
+func (a *SitcomApplication) getOpenBadge(badgeKey []byte) (*credential.OpenBadgeCredential, error) {
+	vc, identity, err := a.getCredential(badgeKey)
+	if err != nil || vc == nil {
+		return nil, err
+	}
+
+	var badge GiveBadge
+	if err := json.Unmarshal(a.state.db.Get(badgeKey), &badge); err != nil {
+		return nil, err
+	}
+
+	competence, err := a.getCompetence(badge.CompetenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	achievement := credential.Achievement{
+		ID:              credential.AchievementID(vc.Proof.ChainID, competence.ID),
+		AchievementType: competence.AchievementType,
+		Name:            competence.Name,
+		Description:     competence.Description,
+		Criteria:        credential.Criteria{Narrative: competence.Criteria},
+		Image:           credential.NewImage(competence.Image, competence.ImageHash),
+	}
+
+	issuer := credential.Profile{Name: vc.Issuer}
+	if identity != nil && identity.Profile != nil {
+		issuer.Name = identity.Profile.Name
+		issuer.URL = identity.Profile.URL
+		issuer.Email = identity.Profile.Email
+		issuer.Description = identity.Profile.Description
+	}
+
+	evidence := make([]credential.Evidence, 0, len(badge.Evidence))
+	for _, item := range badge.Evidence {
+		evidence = append(evidence, credential.NewEvidence(item.URI, item.Hash, item.Size))
+	}
+
+	return credential.NewOpenBadge(vc, achievement, issuer, badge.Narrative, evidence), nil
+}
+
+// queryOpenBadges return every badge of a student as Open Badges 3.0
+// assertions in issuance order, data is a StudentProof
+func (a *SitcomApplication) queryOpenBadges(data []byte) (res types.ResponseQuery) {
+	studentID, resCode, resLog := provenStudent(data)
+	if resCode != code.CodeTypeOK {
+		res.Code = resCode
+		res.Log = resLog
+		return
+	}
+
+	badgeKeys, err := a.getPortfolio(studentID)
+	if err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal portfolio"
+		return
+	}
+
+	assertions := make([]*credential.OpenBadgeCredential, 0, len(badgeKeys))
+	for _, badgeKey := range badgeKeys {
+		assertion, err := a.getOpenBadge([]byte(badgeKey))
+		if err != nil {
+			res.Code = code.CodeTypeUnmarshalError
+			res.Log = err.Error()
+			return
+		}
+		if assertion != nil {
+			assertions = append(assertions, assertion)
+		}
+	}
+
+	res.Value, err = json.Marshal(assertions)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal assertions"
+		return
+	}
+
+	if len(assertions) == 0 {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/credential"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+func (ta *testApp) credential(badge GiveBadge) (*credential.Credential, []byte) {
+	ta.t.Helper()
+
+	key, err := BadgeKey(&badge)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	res := ta.query("/credential", string(key))
+	if res.Code != code.CodeTypeOK || res.Log != "exists" {
+		ta.t.Fatalf("/credential %s: %s", key, res.Log)
+	}
+
+	var vc credential.Credential
+	if err := json.Unmarshal(res.Value, &vc); err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return &vc, res.Value
+}
+
+func TestCredentialVerify(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: badge.StudentID, CompetenceID: 2, Semester: 1}, admin)
+
+	vc, _ := ta.credential(badge)
+	if err := credential.Verify(vc, ta.app); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []struct {
+		name   string
+		tamper func(vc *credential.Credential)
+	}{
+		{"another competence", func(vc *credential.Credential) { vc.CredentialSubject.CompetenceID = 2 }},
+		{"another issuer", func(vc *credential.Credential) { vc.Issuer = credential.IssuerID(testChainID, "", make([]byte, 32)) }},
+		{"another chain", func(vc *credential.Credential) { vc.Proof.ChainID = "another-chain" }},
+		{"another date", func(vc *credential.Credential) { vc.IssuanceDate = "2019-01-01T00:00:00Z" }},
+		{"a forged signature", func(vc *credential.Credential) { vc.Proof.Signature[0] ^= 1 }},
+		{"another payload", func(vc *credential.Credential) { vc.Proof.Payload.Params = []byte(`{"student_id":"x"}`) }},
+		{"no payload", func(vc *credential.Credential) { vc.Proof.Payload = nil }},
+	}
+	for _, test := range tampered {
+		vc, _ := ta.credential(badge)
+		test.tamper(vc)
+		if err := credential.Verify(vc, ta.app); err == nil {
+			t.Fatalf("credential with %s verifies", test.name)
+		}
+	}
+}
+
+func TestCredentialOfBatchHidesOtherEntries(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	entries := []GiveBadge{
+		{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1},
+		{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1},
+		{StudentID: ta.student("3"), CompetenceID: 1, Semester: 1},
+	}
+	ta.must(code.CodeTypeOK, "GiveBadgeBatch", GiveBadgeBatch{Entries: entries}, admin)
+
+	vc, raw := ta.credential(entries[1])
+	if err := credential.Verify(vc, ta.app); err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []GiveBadge{entries[0], entries[2]} {
+		if bytes.Contains(raw, []byte(other.StudentID)) {
+			t.Fatalf("credential reveals student %s of the batch: %s", other.StudentID, raw)
+		}
+	}
+	if len(vc.Proof.Path) != 1 || vc.Proof.Path[0].Payload.Method != "GiveBadgeBatch" {
+		t.Fatalf("expected a path through the batch, got %+v", vc.Proof.Path)
+	}
+
+	// the payload of another entry does not lead to the same sign bytes
+	other, _ := ta.credential(entries[2])
+	vc.Proof.Payload = other.Proof.Payload
+	if err := credential.Verify(vc, ta.app); err == nil {
+		t.Fatal("credential verifies with the payload of another entry")
+	}
+}
+
+func TestCredentialOfMessage(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+
+	// a message authorized by the transaction, and a message signed on its own
+	first := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	second := GiveBadge{StudentID: ta.student("2"), CompetenceID: 2, Semester: 1}
+	signed := ta.message("GiveBadge", second)
+	signed.Payload.ChainId = testChainID
+	signed.Payload.Version = SignVersion
+
+	payload := ta.payload(MultiMessageMethod, "")
+	signed.Signatures = ta.sign(MessagePayload(payload, 2, signed.Payload), admin)
+	payload.Params = []byte(ta.messages(
+		ta.message("CreateActivity", Activity{ID: 1, Title: "Hackathon"}),
+		ta.message("GiveBadge", first),
+		signed,
+	))
+	if res := ta.deliverTx(ta.encode(&protoTm.Tx{Payload: payload, Signatures: ta.sign(payload, admin, organizer)})); res.Code != code.CodeTypeOK {
+		t.Fatalf("expected code %d, got %d: %s", code.CodeTypeOK, res.Code, res.Log)
+	}
+
+	for _, badge := range []GiveBadge{first, second} {
+		vc, raw := ta.credential(badge)
+		if err := credential.Verify(vc, ta.app); err != nil {
+			t.Fatalf("badge of competence %d: %v", badge.CompetenceID, err)
+		}
+		if bytes.Contains(raw, []byte("Hackathon")) {
+			t.Fatalf("credential reveals another message: %s", raw)
+		}
+	}
+}
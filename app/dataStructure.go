@@ -3,20 +3,362 @@ package app
 // SetValidatorParam for updating new validator
 type SetValidatorParam struct {
 	PublicKey []byte `json:"public_key"`
+	KeyType   string `json:"key_type"`
 	Power     int64  `json:"power"`
 }
 
 // GiveBadge for adding new data
 type GiveBadge struct {
-	StudentID    string `json:"student_id"`
-	CompetenceID uint32 `json:"competence_id"`
-	Semester     uint32 `json:"semester"`
-	Giver        []byte `json:"-"`
+	StudentID     string     `json:"student_id"`
+	CompetenceID  uint32     `json:"competence_id"`
+	Semester      uint32     `json:"semester"`
+	Narrative     string     `json:"narrative,omitempty"`
+	Evidence      []Evidence `json:"evidence,omitempty"`
+	PortfolioLeaf string     `json:"portfolio_leaf,omitempty"`
+	Giver         []byte     `json:"-"`
+}
+
+// Evidence reference a file attested with a badge or an approval by its content hash
+type Evidence struct {
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	URI      string `json:"uri,omitempty"`
 }
 
 // ApproveActivity for approving an activity
 type ApproveActivity struct {
-	StudentID  string `json:"student_id"`
+	StudentID       string            `json:"student_id"`
+	ActivityID      uint32            `json:"activity_id"`
+	Evidence        []Evidence        `json:"evidence,omitempty"`
+	PortfolioLeaves map[uint32]string `json:"portfolio_leaves,omitempty"`
+	Approver        []byte            `json:"-"`
+}
+
+// MultisigAccount is an M-of-N signer account
+type MultisigAccount struct {
+	Name       string   `json:"name"`
+	Threshold  int      `json:"threshold"`
+	PublicKeys [][]byte `json:"public_keys"`
+}
+
+// SignPolicy require a multisig account to sign a method,
+// optionally only for one competence when method is GiveBadge
+type SignPolicy struct {
+	Method       string `json:"method"`
+	CompetenceID uint32 `json:"competence_id,omitempty"`
+	Account      string `json:"account"`
+}
+
+// AssignRoleParam for granting or revoking a role of a public key
+type AssignRoleParam struct {
+	PublicKey []byte `json:"public_key"`
+	Role      string `json:"role"`
+	Revoke    bool   `json:"revoke,omitempty"`
+}
+
+// BadgeProposal is a badge waiting for approval before it is given
+type BadgeProposal struct {
+	ID        uint64             `json:"id"`
+	Badge     GiveBadge          `json:"badge"`
+	Proposer  []byte             `json:"proposer"`
+	Height    int64              `json:"height"`
+	ExpiresAt int64              `json:"expires_at"`
+	Approvals []ProposalApproval `json:"approvals"`
+	Status    string             `json:"status"`
+}
+
+// ProposalApproval records who approved a proposal and when
+type ProposalApproval struct {
+	Approver []byte `json:"approver"`
+	Height   int64  `json:"height"`
+}
+
+// ApproveProposalParam for approving a badge proposal
+type ApproveProposalParam struct {
+	ProposalID uint64 `json:"proposal_id"`
+}
+
+// Identity is an issuer with a current key and keys it used before
+type Identity struct {
+	ID          string         `json:"id"`
+	PublicKey   []byte         `json:"public_key"`
+	KeyType     string         `json:"key_type"`
+	Profile     *IssuerProfile `json:"profile,omitempty"`
+	SinceHeight int64          `json:"since_height"`
+	History     []KeyRecord    `json:"history"`
+}
+
+// IssuerProfile describe an issuer in exported badges
+type IssuerProfile struct {
+	Name        string `json:"name"`
+	URL         string `json:"url,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// KeyRecord is a key which was active from FromHeight until ToHeight
+type KeyRecord struct {
+	PublicKey  []byte `json:"public_key"`
+	KeyType    string `json:"key_type"`
+	FromHeight int64  `json:"from_height"`
+	ToHeight   int64  `json:"to_height"`
+}
+
+// IdentityKeyParam for registering an identity or rotating its key
+type IdentityKeyParam struct {
+	ID        string         `json:"id"`
+	PublicKey []byte         `json:"public_key"`
+	KeyType   string         `json:"key_type"`
+	Profile   *IssuerProfile `json:"profile,omitempty"`
+}
+
+// BadgeIssuance records the transaction which gave a badge by its hash,
+// and where the badge's payload is inside it, see protocol.Prove
+type BadgeIssuance struct {
+	Height   int64    `json:"height"`
+	Time     int64    `json:"time,omitempty"`
+	Signers  [][]byte `json:"signers"`
+	TxHash   string   `json:"tx_hash"`
+	Location []int    `json:"location,omitempty"`
+}
+
+// SignerStatus is a signer of a badge checked against identities
+type SignerStatus struct {
+	PublicKey   []byte `json:"public_key"`
+	Identity    string `json:"identity,omitempty"`
+	Active      bool   `json:"active"`
+	Verified    bool   `json:"verified"`
+	Revoked     bool   `json:"revoked"`
+	Compromised bool   `json:"compromised"`
+}
+
+// BadgeVerification is a result of verifying a badge issuance
+type BadgeVerification struct {
+	BadgeKey  string         `json:"badge_key"`
+	Height    int64          `json:"height"`
+	Time      int64          `json:"time,omitempty"`
+	TxHash    string         `json:"tx_hash"`
+	SignBytes []byte         `json:"sign_bytes"`
+	Signers   []SignerStatus `json:"signers"`
+	Valid     bool           `json:"valid"`
+}
+
+// KeyRevocation records a revoked key and since when it is compromised
+type KeyRevocation struct {
+	PublicKey     []byte `json:"public_key"`
+	Height        int64  `json:"height"`
+	CompromisedAt int64  `json:"compromised_at"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Competence is an entry of the competence catalog
+type Competence struct {
+	ID              uint32     `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Level           uint32     `json:"level"`
+	Category        string     `json:"category"`
+	Department      string     `json:"department"`
+	AchievementType string     `json:"achievement_type,omitempty"`
+	Criteria        string     `json:"criteria,omitempty"`
+	Image           string     `json:"image,omitempty"`
+	ImageHash       string     `json:"image_hash,omitempty"`
+	Deprecated      bool       `json:"deprecated"`
+	AwardRule       *AwardRule `json:"award_rule,omitempty"`
+	Prerequisites   []uint32   `json:"prerequisites"`
+	CreatedHeight   int64      `json:"created_height"`
+	UpdatedHeight   int64      `json:"updated_height"`
+}
+
+// AwardRule gives a competence automatically once a student has
+// Count approved activities tagged Tag within the same semester
+type AwardRule struct {
+	Tag   string `json:"tag"`
+	Count uint32 `json:"count"`
+}
+
+// DeprecateCompetenceParam for deprecating a competence
+type DeprecateCompetenceParam struct {
+	ID uint32 `json:"id"`
+}
+
+// Activity is an activity organized for students
+type Activity struct {
+	ID                uint32   `json:"id"`
+	Title             string   `json:"title"`
+	Organizer         []byte   `json:"organizer"`
+	Delegates         [][]byte `json:"delegates"`
+	Start             int64    `json:"start"`
+	End               int64    `json:"end"`
+	Capacity          uint32   `json:"capacity"`
+	Competences       []uint32 `json:"competences"`
+	Tags              []string `json:"tags"`
+	Semester          uint32   `json:"semester"`
+	State             string   `json:"state"`
+	Enrolled          uint32   `json:"enrolled"`
+	RequireAttendance bool     `json:"require_attendance"`
+	CreatedHeight     int64    `json:"created_height"`
+	UpdatedHeight     int64    `json:"updated_height"`
+}
+
+// ActivityStateParam for moving an activity to the next state
+type ActivityStateParam struct {
+	ActivityID uint32 `json:"activity_id"`
+	State      string `json:"state"`
+}
+
+// EnrollmentParam for enrolling a student or recording attendance
+type EnrollmentParam struct {
 	ActivityID uint32 `json:"activity_id"`
-	Approver   []byte `json:"-"`
+	StudentID  string `json:"student_id"`
+}
+
+// Enrollment is a student who joined an activity
+type Enrollment struct {
+	ActivityID       uint32 `json:"activity_id"`
+	StudentID        string `json:"student_id"`
+	EnrolledHeight   int64  `json:"enrolled_height"`
+	EnrolledBy       []byte `json:"enrolled_by"`
+	Attended         bool   `json:"attended"`
+	AttendanceHeight int64  `json:"attendance_height,omitempty"`
+	RecordedBy       []byte `json:"recorded_by,omitempty"`
+}
+
+// CompetenceProgress is a student's position at one competence of the graph
+type CompetenceProgress struct {
+	ID            uint32   `json:"id"`
+	Name          string   `json:"name"`
+	Level         uint32   `json:"level"`
+	Prerequisites []uint32 `json:"prerequisites"`
+	Earned        bool     `json:"earned"`
+	Missing       []uint32 `json:"missing"`
+	Eligible      bool     `json:"eligible"`
+}
+
+// Semester is an entry of the academic calendar, Start and End are unix times
+type Semester struct {
+	ID    uint32 `json:"id"`
+	Name  string `json:"name"`
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+}
+
+// SemesterStats counts badges given in a semester
+type SemesterStats struct {
+	Semester     uint32            `json:"semester"`
+	Badges       uint64            `json:"badges"`
+	Students     uint64            `json:"students"`
+	ByCompetence map[uint32]uint64 `json:"by_competence"`
+}
+
+// Portfolio is the merkle root of a student's salted portfolio leaves
+type Portfolio struct {
+	StudentID string `json:"student_id"`
+	Root      []byte `json:"root"`
+}
+
+// StudentRecord reference a student's personal data kept encrypted off chain
+type StudentRecord struct {
+	StudentID string `json:"student_id"`
+	PIIHash   string `json:"pii_hash"`
+	Height    int64  `json:"height"`
+}
+
+// ForgetStudentParam struct
+type ForgetStudentParam struct {
+	StudentID string `json:"student_id"`
+	Reason    string `json:"reason"`
+}
+
+// Tombstone record that a student's personal data has been erased
+type Tombstone struct {
+	StudentID string `json:"student_id"`
+	PIIHash   string `json:"pii_hash,omitempty"`
+	Reason    string `json:"reason"`
+	Height    int64  `json:"height"`
+}
+
+// GiveBadgeBatch gives many badges under one signature, all or none of them
+type GiveBadgeBatch struct {
+	Entries []GiveBadge `json:"entries"`
+}
+
+// BatchResult is the result of one entry of a batch
+type BatchResult struct {
+	Index int    `json:"index"`
+	Code  uint32 `json:"code"`
+	Log   string `json:"log"`
+}
+
+// MessageResult is the result of one message of a multi-message transaction
+type MessageResult struct {
+	Index  int    `json:"index"`
+	Method string `json:"method"`
+	Code   uint32 `json:"code"`
+	Log    string `json:"log"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+// ActivityApproval index a student's approved activity with its approvers
+type ActivityApproval struct {
+	ActivityID  uint32   `json:"activity_id"`
+	ActivityKey string   `json:"activity_key"`
+	Height      int64    `json:"height"`
+	Time        int64    `json:"time,omitempty"`
+	Approvers   [][]byte `json:"approvers"`
+}
+
+// TranscriptBadge is a badge of a transcript with its issuance
+type TranscriptBadge struct {
+	BadgeKey string         `json:"badge_key"`
+	Badge    GiveBadge      `json:"badge"`
+	Height   int64          `json:"height"`
+	Time     int64          `json:"time,omitempty"`
+	TxHash   string         `json:"tx_hash"`
+	Signers  []SignerStatus `json:"signers"`
+	Valid    bool           `json:"valid"`
+}
+
+// TranscriptActivity is an approved activity of a transcript
+type TranscriptActivity struct {
+	ActivityID  uint32         `json:"activity_id"`
+	ActivityKey string         `json:"activity_key"`
+	Title       string         `json:"title,omitempty"`
+	Semester    uint32         `json:"semester,omitempty"`
+	Height      int64          `json:"height"`
+	Time        int64          `json:"time,omitempty"`
+	Approvers   []SignerStatus `json:"approvers"`
+	Valid       bool           `json:"valid"`
+}
+
+// Transcript is a student's complete record, revoked lists the keys of
+// badges and approvals whose signers are no longer trusted
+type Transcript struct {
+	StudentID  string               `json:"student_id"`
+	Height     int64                `json:"height"`
+	Badges     []TranscriptBadge    `json:"badges"`
+	Activities []TranscriptActivity `json:"activities"`
+	Revoked    []string             `json:"revoked"`
+	Issuers    map[string]*Identity `json:"issuers"`
+}
+
+// ActivityParticipation counts students of an activity, rates are
+// relative to the number enrolled
+type ActivityParticipation struct {
+	ActivityID     uint32  `json:"activity_id"`
+	Title          string  `json:"title"`
+	Semester       uint32  `json:"semester"`
+	Capacity       uint32  `json:"capacity"`
+	Enrolled       uint64  `json:"enrolled"`
+	Attended       uint64  `json:"attended"`
+	Approved       uint64  `json:"approved"`
+	AttendanceRate float64 `json:"attendance_rate"`
+	ApprovalRate   float64 `json:"approval_rate"`
+}
+
+// IssuerCount is the number of badges an issuer gave
+type IssuerCount struct {
+	Issuer string `json:"issuer"`
+	Badges uint64 `json:"badges"`
 }
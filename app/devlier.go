@@ -3,37 +3,92 @@ package app
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/protocol"
 	"github.com/tendermint/tendermint/abci/types"
 )
 
-func (a *SitcomApplication) addNewService(payload []byte) (res types.ResponseDeliverTx, err error) {
+const (
+	// ServicePrefix define the prefix of service keys other than sitcompetence
+	ServicePrefix string = "service:"
+)
+
+// parseService split the params of AddNewService, "<name>:<value>"
+func parseService(payload []byte) ([]byte, []byte, error) {
 	parts := bytes.Split(payload, []byte(":"))
-	if len(parts) != 2 {
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return nil, nil, errors.New("invalid payload format")
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// addNewService set the sitcompetence key once, later it moves with RotateKey,
+// other services are kept under ServicePrefix so they cannot overwrite state
+func (a *SitcomApplication) addNewService(payload []byte) (res types.ResponseDeliverTx, err error) {
+	name, value, err := parseService(payload)
+	if err != nil {
 		res.Code = code.CodeTypeInvalidMethod
-		res.Log = "invalid payload format"
+		res.Log = err.Error()
 		a.logger.Infoln(res.Log)
-		return
+		return res, err
 	}
 
-	a.state.db.Set(parts[0], parts[1])
+	key := append([]byte(ServicePrefix), name...)
+	if bytes.Equal(name, adminKey) {
+		if a.state.db.Has(adminKey) {
+			res.Code = code.CodeTypeDuplicateKey
+			res.Log = "sitcompetence key is already set, rotate it with RotateKey"
+			a.logger.Infoln(res.Log)
+			return res, errors.New(res.Log)
+		}
+		key = adminKey
+	}
+
+	a.state.db.Set(key, value)
 	res.Code = code.CodeTypeOK
 	return res, nil
 }
 
-func (a *SitcomApplication) giveBadge(payload []byte) (res types.ResponseDeliverTx, err error) {
-	var sorted map[string]interface{}
-	if err := json.Unmarshal(payload, &sorted); err != nil {
+// BadgeKey return the key of a badge, a JSON object of student, competence
+// and semester with sorted keys, so the same badge always has the same key
+func BadgeKey(badge *GiveBadge) ([]byte, error) {
+	return protocol.BadgeKey(badge.StudentID, badge.CompetenceID, badge.Semester)
+}
+
+func (a *SitcomApplication) giveBadge(payload []byte, txObj *protoTm.Tx, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var badge GiveBadge
+	if err := json.Unmarshal(payload, &badge); err != nil {
 		res.Code = code.CodeTypeUnmarshalError
 		res.Log = "error when unmarshaling params"
 		a.logger.Infoln(res.Log)
 		return res, err
 	}
 
-	delete(sorted, "giver")
+	if res.Code, res.Log = a.checkStudent(badge.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if err := validateEvidence(badge.Evidence); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := validatePortfolioLeaf(badge.PortfolioLeaf); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
 
-	badgeKey, err := json.Marshal(sorted)
+	badgeKey, err := BadgeKey(&badge)
 	if err != nil {
 		res.Code = code.CodeTypeEncodingError
 		res.Log = "error when marshal badgeKey"
@@ -41,8 +96,51 @@ func (a *SitcomApplication) giveBadge(payload []byte) (res types.ResponseDeliver
 		return res, err
 	}
 
+	if a.state.db.Has(badgeKey) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = fmt.Sprintf("badge %s has already been given", badgeKey)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if res.Code, res.Log = a.checkCompetence(badge.CompetenceID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if res.Code, res.Log = a.checkSemesterOpen(badge.Semester, a.signedByRole(RoleHistoricalImport, txObj)); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if res.Code, res.Log = a.checkPrerequisites(&badge, a.signedByRole(RolePrerequisiteOverride, txObj)); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
 	a.logger.Infof("k: %s, v: %s\n", badgeKey, payload)
 	a.state.db.Set(badgeKey, payload)
+	a.state.db.Set(studentBadgeKey(&badge), badgeKey)
+	a.state.db.Set(semesterBadgeKey(&badge), badgeKey)
+	if err := a.appendPortfolio(&badge, badgeKey); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal portfolio"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+	if err := a.recordIssuance(badgeKey, txObj, signers); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal issuance"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+	if err := a.countBadge(&badge, signers); err != nil {
+		res.Code = code.CodeTypeUnknownError
+		res.Log = "error when counting badge"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
 	a.state.Size++
 	res.Code = code.CodeTypeOK
 	res.Log = "success"
@@ -50,7 +148,7 @@ func (a *SitcomApplication) giveBadge(payload []byte) (res types.ResponseDeliver
 	return res, nil
 }
 
-func (a *SitcomApplication) approveActivity(payload []byte) (res types.ResponseDeliverTx, err error) {
+func (a *SitcomApplication) approveActivity(payload []byte, txObj *protoTm.Tx, signers [][]byte) (res types.ResponseDeliverTx, err error) {
 	var sorted map[string]interface{}
 	if err := json.Unmarshal(payload, &sorted); err != nil {
 		res.Code = code.CodeTypeUnmarshalError
@@ -59,7 +157,48 @@ func (a *SitcomApplication) approveActivity(payload []byte) (res types.ResponseD
 		return res, err
 	}
 
+	var approval ApproveActivity
+	if err := json.Unmarshal(payload, &approval); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res.Code, res.Log = a.checkStudent(approval.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if err := validateEvidence(approval.Evidence); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	for _, leaf := range approval.PortfolioLeaves {
+		if err := validatePortfolioLeaf(leaf); err != nil {
+			res.Code = code.CodeTypeInvalidParams
+			res.Log = err.Error()
+			a.logger.Infoln(res.Log)
+			return res, err
+		}
+	}
+
+	if res.Code, res.Log = a.checkApprovableActivity(approval.ActivityID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if res.Code, res.Log = a.checkAttendance(approval.ActivityID, approval.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
 	delete(sorted, "approver")
+	delete(sorted, "evidence")
+	delete(sorted, "portfolio_leaves")
 
 	activityKey, err := json.Marshal(sorted)
 	if err != nil {
@@ -69,8 +208,30 @@ func (a *SitcomApplication) approveActivity(payload []byte) (res types.ResponseD
 		return res, err
 	}
 
+	approved := a.state.db.Has(activityKey)
+
 	a.logger.Infof("k: %s, v: %s\n", activityKey, payload)
 	a.state.db.Set(activityKey, payload)
+
+	if !approved {
+		events, err := a.awardBadges(&approval, txObj, signers)
+		if err != nil {
+			res.Code = code.CodeTypeUnknownError
+			res.Log = err.Error()
+			a.logger.Infoln(res.Log)
+			return res, err
+		}
+		res.Events = events
+
+		if err := a.indexApproval(&approval, activityKey, signers); err != nil {
+			res.Code = code.CodeTypeEncodingError
+			res.Log = "error when marshal approval"
+			a.logger.Infoln(res.Log)
+			return res, err
+		}
+		a.incrCounter(statsActivityKey(approval.ActivityID, "approved"))
+	}
+
 	a.state.Size++
 	res.Code = code.CodeTypeOK
 	res.Log = "success"
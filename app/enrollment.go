@@ -0,0 +1,241 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// EnrollmentPrefix define the prefix of enrollment keys
+	EnrollmentPrefix string = "enrollment:"
+)
+
+func enrollmentPrefix(activityID uint32) []byte {
+	return []byte(EnrollmentPrefix + strconv.FormatUint(uint64(activityID), 10) + ":")
+}
+
+func enrollmentKey(activityID uint32, studentID string) []byte {
+	return append(enrollmentPrefix(activityID), studentID...)
+}
+
+func (a *SitcomApplication) getEnrollment(activityID uint32, studentID string) (*Enrollment, error) {
+	value := a.state.db.Get(enrollmentKey(activityID, studentID))
+	if value == nil {
+		return nil, fmt.Errorf("student %s is not enrolled in activity %d", studentID, activityID)
+	}
+
+	var enrollment Enrollment
+	if err := json.Unmarshal(value, &enrollment); err != nil {
+		return nil, err
+	}
+
+	return &enrollment, nil
+}
+
+func (a *SitcomApplication) setEnrollment(enrollment *Enrollment) error {
+	value, err := json.Marshal(enrollment)
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(enrollmentKey(enrollment.ActivityID, enrollment.StudentID), value)
+	return nil
+}
+
+// checkEnrollSignature verify that the student, through the identity
+// registered with the student ID, or the organizer or a delegate signed
+func (a *SitcomApplication) checkEnrollSignature(payload *protoTm.Payload, signBytes []byte, signatures []*protoTm.Signature) ([][]byte, uint32, string) {
+	var param EnrollmentParam
+	if err := json.Unmarshal(payload.Params, &param); err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal params"
+	}
+
+	if identity, err := a.getIdentity(param.StudentID); err == nil && !a.isRevoked(identity.PublicKey) {
+		for _, sig := range signatures {
+			if bytes.Equal(sig.PublicKey, identity.PublicKey) && verifySignature(identity.KeyType, identity.PublicKey, signBytes, sig.Signature) {
+				return [][]byte{identity.PublicKey}, code.CodeTypeOK, ""
+			}
+		}
+	}
+
+	return a.checkActivitySignature(payload, signBytes, signatures)
+}
+
+func (a *SitcomApplication) enrollActivity(payload []byte, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var param EnrollmentParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res.Code, res.Log = a.checkStudent(param.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	activity, err := a.getActivity(param.ActivityID)
+	if err != nil {
+		res.Code = code.CodeTypeNotFound
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if activity.State != ActivityOpen {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = fmt.Sprintf("activity %d is %s", activity.ID, activity.State)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if a.state.db.Has(enrollmentKey(param.ActivityID, param.StudentID)) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = fmt.Sprintf("student %s is already enrolled in activity %d", param.StudentID, activity.ID)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if activity.Capacity != 0 && activity.Enrolled >= activity.Capacity {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = fmt.Sprintf("activity %d is full", activity.ID)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	enrollment := Enrollment{
+		ActivityID:     param.ActivityID,
+		StudentID:      param.StudentID,
+		EnrolledHeight: a.state.Height,
+		EnrolledBy:     signers[0],
+	}
+	if err := a.setEnrollment(&enrollment); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal enrollment"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	activity.Enrolled++
+	if err := a.setActivity(activity); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal activity"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) recordAttendance(payload []byte, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var param EnrollmentParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res.Code, res.Log = a.checkApprovableActivity(param.ActivityID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	enrollment, err := a.getEnrollment(param.ActivityID, param.StudentID)
+	if err != nil {
+		res.Code = code.CodeTypeNotFound
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if enrollment.Attended {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = "attendance has already been recorded"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	enrollment.Attended = true
+	enrollment.AttendanceHeight = a.state.Height
+	enrollment.RecordedBy = signers[0]
+	if err := a.setEnrollment(enrollment); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal enrollment"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+	a.incrCounter(statsActivityKey(param.ActivityID, "attended"))
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// checkAttendance return an error code when the activity requires
+// attendance and the student has not attended
+func (a *SitcomApplication) checkAttendance(activityID uint32, studentID string) (uint32, string) {
+	activity, err := a.getActivity(activityID)
+	if err != nil {
+		return code.CodeTypeNotFound, err.Error()
+	}
+
+	if !activity.RequireAttendance {
+		return code.CodeTypeOK, ""
+	}
+
+	enrollment, err := a.getEnrollment(activityID, studentID)
+	if err != nil || !enrollment.Attended {
+		return code.CodeTypeInvalidParams, fmt.Sprintf("student %s has not attended activity %d", studentID, activityID)
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+// queryEnrollments list enrollments of an activity
+func (a *SitcomApplication) queryEnrollments(data []byte) (res types.ResponseQuery) {
+	id, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid activity id"
+		return
+	}
+
+	enrollments := make([]*Enrollment, 0)
+	itr := dbm.IteratePrefix(a.state.db, enrollmentPrefix(uint32(id)))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var enrollment Enrollment
+		if err := json.Unmarshal(itr.Value(), &enrollment); err != nil {
+			continue
+		}
+		enrollments = append(enrollments, &enrollment)
+	}
+
+	value, err := json.Marshal(enrollments)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal enrollments"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = value
+	return
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestEnrollment(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin)
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Capacity: 2}, organizer)
+
+	first := EnrollmentParam{ActivityID: 1, StudentID: ta.student("1")}
+	ta.must(code.CodeTypeInvalidParams, "EnrollActivity", first, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	// a student enrolls with the identity registered under the student ID
+	studentKey, studentSigner := ta.newKey()
+	ta.must(code.CodeTypeOK, "RegisterIdentity", IdentityKeyParam{ID: first.StudentID, PublicKey: studentKey}, admin)
+	ta.must(code.CodeTypeUnauthorized, "EnrollActivity", EnrollmentParam{ActivityID: 1, StudentID: ta.student("2")}, studentSigner)
+	ta.must(code.CodeTypeOK, "EnrollActivity", first, studentSigner)
+	ta.must(code.CodeTypeDuplicateKey, "EnrollActivity", first, organizer)
+
+	ta.must(code.CodeTypeInvalidParams, "EnrollActivity", EnrollmentParam{ActivityID: 1, StudentID: "not a commitment"}, organizer)
+	ta.must(code.CodeTypeOK, "EnrollActivity", EnrollmentParam{ActivityID: 1, StudentID: ta.student("2")}, organizer)
+	ta.must(code.CodeTypeInvalidParams, "EnrollActivity", EnrollmentParam{ActivityID: 1, StudentID: ta.student("3")}, organizer)
+
+	var enrollments []Enrollment
+	ta.queryJSON("/enrollments", "1", &enrollments)
+	if len(enrollments) != 2 {
+		t.Fatalf("expected 2 enrollments, got %+v", enrollments)
+	}
+	for _, enrollment := range enrollments {
+		if enrollment.StudentID == first.StudentID && !bytes.Equal(enrollment.EnrolledBy, studentKey) {
+			t.Fatalf("expected the student's enrollment by the student, got %+v", enrollment)
+		}
+	}
+
+	var activity Activity
+	ta.queryJSON("/activity", "1", &activity)
+	if activity.Enrolled != 2 {
+		t.Fatalf("expected 2 enrolled, got %d", activity.Enrolled)
+	}
+}
+
+func TestAttendance(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin)
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Hackathon", RequireAttendance: true}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	attended := EnrollmentParam{ActivityID: 1, StudentID: ta.student("1")}
+	absent := EnrollmentParam{ActivityID: 1, StudentID: ta.student("2")}
+	ta.must(code.CodeTypeOK, "EnrollActivity", attended, organizer)
+	ta.must(code.CodeTypeOK, "EnrollActivity", absent, organizer)
+
+	_, stranger := ta.newKey()
+	ta.must(code.CodeTypeUnauthorized, "RecordAttendance", attended, stranger)
+	ta.must(code.CodeTypeNotFound, "RecordAttendance", EnrollmentParam{ActivityID: 1, StudentID: ta.student("3")}, organizer)
+	ta.must(code.CodeTypeOK, "RecordAttendance", attended, organizer)
+	ta.must(code.CodeTypeDuplicateKey, "RecordAttendance", attended, organizer)
+
+	// an activity requiring attendance is approved only for students who attended
+	ta.must(code.CodeTypeInvalidParams, "ApproveActivity", ApproveActivity{StudentID: absent.StudentID, ActivityID: 1}, organizer)
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: attended.StudentID, ActivityID: 1}, organizer)
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// StudentRecordPrefix define the prefix of off-chain personal data references
+	StudentRecordPrefix string = "studentrecord:"
+
+	// TombstonePrefix define the prefix of forgotten student keys
+	TombstonePrefix string = "tombstone:"
+)
+
+func studentRecordKey(studentID string) []byte {
+	return []byte(StudentRecordPrefix + studentID)
+}
+
+func tombstoneKey(studentID string) []byte {
+	return []byte(TombstonePrefix + studentID)
+}
+
+func (a *SitcomApplication) getStudentRecord(studentID string) (*StudentRecord, error) {
+	value := a.state.db.Get(studentRecordKey(studentID))
+	if value == nil {
+		return nil, nil
+	}
+
+	var record StudentRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (a *SitcomApplication) getTombstone(studentID string) (*Tombstone, error) {
+	value := a.state.db.Get(tombstoneKey(studentID))
+	if value == nil {
+		return nil, nil
+	}
+
+	var tombstone Tombstone
+	if err := json.Unmarshal(value, &tombstone); err != nil {
+		return nil, err
+	}
+
+	return &tombstone, nil
+}
+
+// checkStudent return an error code unless the student ID is a commitment
+// of a student who has not been forgotten
+func (a *SitcomApplication) checkStudent(studentID string) (uint32, string) {
+	if resCode, log := checkStudentID(studentID); resCode != code.CodeTypeOK {
+		return resCode, log
+	}
+
+	if a.state.db.Has(tombstoneKey(studentID)) {
+		return code.CodeTypeForgotten, fmt.Sprintf("student %s has been forgotten", studentID)
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+// setStudentRecord reference the hash of a student's encrypted personal data,
+// the data itself never reaches the chain
+func (a *SitcomApplication) setStudentRecord(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var record StudentRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res.Code, res.Log = a.checkStudent(record.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if hashed, err := hex.DecodeString(record.PIIHash); err != nil || len(hashed) != 32 {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "pii_hash must be a sha256 in hex"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	record.Height = a.state.Height
+	value, err := json.Marshal(record)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal student record"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.db.Set(studentRecordKey(record.StudentID), value)
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// forgetStudent remove the reference to a student's personal data and leave
+// a tombstone, badges keyed by the student's commitment stay on chain so the
+// ledger stays consistent. The node's off-chain store, if any, deletes the
+// student's key so the personal data can no longer be decrypted.
+func (a *SitcomApplication) forgetStudent(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var param ForgetStudentParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res.Code, res.Log = a.checkStudent(param.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	record, err := a.getStudentRecord(param.StudentID)
+	if err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal student record"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	tombstone := Tombstone{
+		StudentID: param.StudentID,
+		Reason:    param.Reason,
+		Height:    a.state.Height,
+	}
+	if record != nil {
+		tombstone.PIIHash = record.PIIHash
+	}
+
+	value, err := json.Marshal(tombstone)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal tombstone"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.db.Delete(studentRecordKey(param.StudentID))
+	a.state.db.Set(tombstoneKey(param.StudentID), value)
+
+	if a.pii != nil {
+		a.afterWrite(func() { a.pii.Forget(param.StudentID, tombstone.PIIHash) })
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// queryStudentRecord return the reference to a student's personal data,
+// or the tombstone when the student has been forgotten
+func (a *SitcomApplication) queryStudentRecord(data []byte) (res types.ResponseQuery) {
+	studentID := string(data)
+
+	tombstone, err := a.getTombstone(studentID)
+	if err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal tombstone"
+		return
+	}
+
+	if tombstone != nil {
+		res.Key = tombstoneKey(studentID)
+		res.Value, err = json.Marshal(tombstone)
+		if err != nil {
+			res.Code = code.CodeTypeEncodingError
+			res.Log = "error when marshal tombstone"
+			return
+		}
+
+		res.Log = "forgotten"
+		return
+	}
+
+	record, err := a.getStudentRecord(studentID)
+	if err != nil || record == nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = studentRecordKey(studentID)
+	res.Value, err = json.Marshal(record)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal student record"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"testing"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/pii"
+)
+
+func TestForgetStudentErasesPersonalData(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	store := pii.NewStoreWithDB(dbm.NewMemDB())
+	ta.app.SetPIIStore(store)
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	studentID := ta.student("1")
+	ref, err := store.Put(studentID, []byte("name: Somchai"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.must(code.CodeTypeOK, "SetStudentRecord", StudentRecord{StudentID: studentID, PIIHash: ref}, admin)
+	if res := ta.query("/student_record", studentID); res.Log != "exists" {
+		t.Fatalf("expected the student record, got %s", res.Log)
+	}
+
+	ta.must(code.CodeTypeOK, "ForgetStudent", ForgetStudentParam{StudentID: studentID, Reason: "request of the student"}, admin)
+	if _, err := store.Get(studentID, ref); err == nil {
+		t.Fatal("personal data of a forgotten student can still be read")
+	}
+	if res := ta.query("/student_record", studentID); res.Log != "forgotten" {
+		t.Fatalf("expected a tombstone, got %s", res.Log)
+	}
+
+	ta.must(code.CodeTypeForgotten, "GiveBadge", GiveBadge{StudentID: studentID, CompetenceID: 1, Semester: 1}, admin)
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/saguywalker/sitcomchain/blobstore"
+)
+
+const (
+	// MaxEvidence is the maximum number of evidence references of a badge or an approval
+	MaxEvidence = 16
+
+	// MaxEvidenceURISize is the maximum length of an evidence URI
+	MaxEvidenceURISize = 2048
+
+	// MaxMimeTypeSize is the maximum length of an evidence MIME type
+	MaxMimeTypeSize = 255
+
+	// MaxEvidenceSize is the maximum size in bytes of an evidence file, the blob store's limit
+	MaxEvidenceSize = blobstore.MaxBlobSize
+)
+
+// validateEvidence check the format and size of evidence references, a hash
+// is a sha256 of the file in lowercase hex so the same file has one reference,
+// and a size is the file's size in bytes so a reviewer can bound a download
+func validateEvidence(evidence []Evidence) error {
+	if len(evidence) > MaxEvidence {
+		return fmt.Errorf("at most %d evidence can be attached", MaxEvidence)
+	}
+
+	seen := make(map[string]bool)
+	for _, item := range evidence {
+		if !isSha256Hex(item.Hash) {
+			return fmt.Errorf("evidence hash %q must be a sha256 in lowercase hex", item.Hash)
+		}
+
+		if seen[item.Hash] {
+			return fmt.Errorf("evidence %s is attached twice", item.Hash)
+		}
+		seen[item.Hash] = true
+
+		if item.Size <= 0 || item.Size > MaxEvidenceSize {
+			return fmt.Errorf("size of evidence %s must be between 1 and %d bytes", item.Hash, MaxEvidenceSize)
+		}
+
+		if len(item.MimeType) > MaxMimeTypeSize {
+			return fmt.Errorf("mime type of evidence %s is too long", item.Hash)
+		}
+
+		mediaType, _, err := mime.ParseMediaType(item.MimeType)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return fmt.Errorf("evidence %s has an invalid mime type %q", item.Hash, item.MimeType)
+		}
+
+		if item.URI == "" {
+			continue
+		}
+
+		if len(item.URI) > MaxEvidenceURISize {
+			return fmt.Errorf("uri of evidence %s is too long", item.Hash)
+		}
+
+		if uri, err := url.Parse(item.URI); err != nil || !uri.IsAbs() {
+			return fmt.Errorf("evidence %s has an invalid uri %q", item.Hash, item.URI)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/credential"
+)
+
+func TestValidateEvidence(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	valid := Evidence{Hash: hash, Size: 1024, MimeType: "application/pdf"}
+
+	many := make([]Evidence, 0, MaxEvidence+1)
+	for i := 0; i <= MaxEvidence; i++ {
+		item := valid
+		item.Hash = fmt.Sprintf("%064x", i)
+		many = append(many, item)
+	}
+
+	tests := []struct {
+		name     string
+		evidence []Evidence
+		valid    bool
+	}{
+		{"no evidence", nil, true},
+		{"a file", []Evidence{valid}, true},
+		{"a file with a uri", []Evidence{{Hash: hash, Size: 1, MimeType: "image/png; charset=binary", URI: "https://example.com/a.png"}}, true},
+		{"the size limit", []Evidence{{Hash: hash, Size: MaxEvidenceSize, MimeType: "video/mp4"}}, true},
+		{"an uppercase hash", []Evidence{{Hash: strings.ToUpper(hash), Size: 1, MimeType: "application/pdf"}}, false},
+		{"a short hash", []Evidence{{Hash: "abcd", Size: 1, MimeType: "application/pdf"}}, false},
+		{"the same file twice", []Evidence{valid, valid}, false},
+		{"an empty file", []Evidence{{Hash: hash, MimeType: "application/pdf"}}, false},
+		{"a file above the limit", []Evidence{{Hash: hash, Size: MaxEvidenceSize + 1, MimeType: "application/pdf"}}, false},
+		{"no mime type", []Evidence{{Hash: hash, Size: 1}}, false},
+		{"a mime type without a subtype", []Evidence{{Hash: hash, Size: 1, MimeType: "pdf"}}, false},
+		{"a relative uri", []Evidence{{Hash: hash, Size: 1, MimeType: "application/pdf", URI: "files/a.pdf"}}, false},
+		{"a long uri", []Evidence{{Hash: hash, Size: 1, MimeType: "application/pdf", URI: "https://example.com/" + strings.Repeat("a", MaxEvidenceURISize)}}, false},
+		{"too many files", many, false},
+	}
+
+	for _, test := range tests {
+		if err := validateEvidence(test.evidence); (err == nil) != test.valid {
+			t.Fatalf("%s: expected valid %v, got %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestEvidenceOfBadgeAndApproval(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Semester: 1}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	invalid := []Evidence{{Hash: "not a hash", Size: 1, MimeType: "application/pdf"}}
+	ta.must(code.CodeTypeInvalidParams, "GiveBadge", GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1, Evidence: invalid}, admin)
+	ta.must(code.CodeTypeInvalidParams, "ApproveActivity", ApproveActivity{StudentID: ta.student("1"), ActivityID: 1, Evidence: invalid}, organizer)
+
+	fileHash, linkHash := strings.Repeat("01", 32), strings.Repeat("02", 32)
+	badge := GiveBadge{
+		StudentID:    ta.student("1"),
+		CompetenceID: 1,
+		Semester:     1,
+		Evidence: []Evidence{
+			{Hash: fileHash, Size: 1024, MimeType: "application/pdf"},
+			{Hash: linkHash, Size: 2048, MimeType: "image/png", URI: "https://evidence.example.com/photo.png"},
+		},
+	}
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+
+	// evidence is attached to an approval without changing which activity it approves
+	approval := ApproveActivity{StudentID: ta.student("1"), ActivityID: 1, Evidence: badge.Evidence[:1]}
+	ta.must(code.CodeTypeOK, "ApproveActivity", approval, organizer)
+	var transcript Transcript
+	ta.queryJSON("/transcript", ta.studentProof("1"), &transcript)
+	if len(transcript.Activities) != 1 || strings.Contains(transcript.Activities[0].ActivityKey, fileHash) {
+		t.Fatalf("expected one approval keyed without its evidence, got %+v", transcript.Activities)
+	}
+
+	// an evidence keeps its hash and size when it has a URI
+	var assertions []credential.OpenBadgeCredential
+	ta.queryJSON("/openbadges", ta.studentProof("1"), &assertions)
+	expected := []credential.Evidence{
+		{ID: "urn:sha256:" + fileHash, Type: []string{"Evidence"}, Hash: fileHash, Size: 1024},
+		{ID: "https://evidence.example.com/photo.png", Type: []string{"Evidence"}, Hash: linkHash, Size: 2048},
+	}
+	if len(assertions) != 1 || !reflect.DeepEqual(assertions[0].Evidence, expected) {
+		t.Fatalf("expected evidence %+v, got %+v", expected, assertions)
+	}
+}
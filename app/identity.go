@@ -0,0 +1,478 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/credential"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// IdentityPrefix define the prefix of identity keys
+	IdentityPrefix string = "identity:"
+
+	// IdentityKeyPrefix define the prefix of public key to identity index
+	IdentityKeyPrefix string = "identitykey:"
+
+	// IssuancePrefix define the prefix of badge issuance keys
+	IssuancePrefix string = "issuance:"
+
+	// IssuanceTxPrefix define the prefix of transactions which gave badges,
+	// keyed by their hash
+	IssuanceTxPrefix string = "issuancetx:"
+)
+
+func identityKey(id string) []byte {
+	return []byte(IdentityPrefix + id)
+}
+
+func identityPublicKeyKey(publicKey []byte) []byte {
+	return []byte(IdentityKeyPrefix + base64.StdEncoding.EncodeToString(publicKey))
+}
+
+func issuanceKey(badgeKey []byte) []byte {
+	hashed := sha256.Sum256(badgeKey)
+	return []byte(IssuancePrefix + hex.EncodeToString(hashed[:]))
+}
+
+func issuanceTxKey(txHash string) []byte {
+	return []byte(IssuanceTxPrefix + txHash)
+}
+
+func (a *SitcomApplication) getIdentity(id string) (*Identity, error) {
+	value := a.state.db.Get(identityKey(id))
+	if value == nil {
+		return nil, fmt.Errorf("identity %s not found", id)
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(value, &identity); err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// identityOf return an identity which owns or owned the public key
+func (a *SitcomApplication) identityOf(publicKey []byte) (*Identity, error) {
+	id := a.state.db.Get(identityPublicKeyKey(publicKey))
+	if id == nil {
+		return nil, nil
+	}
+
+	return a.getIdentity(string(id))
+}
+
+// activeAt report whether the public key was the identity's key at height
+func (identity *Identity) activeAt(publicKey []byte, height int64) bool {
+	if bytes.Equal(identity.PublicKey, publicKey) && height >= identity.SinceHeight {
+		return true
+	}
+
+	for _, record := range identity.History {
+		if bytes.Equal(record.PublicKey, publicKey) && height >= record.FromHeight && height < record.ToHeight {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *SitcomApplication) setIdentity(identity *Identity) error {
+	value, err := json.Marshal(identity)
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(identityKey(identity.ID), value)
+	a.state.db.Set(identityPublicKeyKey(identity.PublicKey), []byte(identity.ID))
+	return nil
+}
+
+func (a *SitcomApplication) registerIdentity(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var param IdentityKeyParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if param.ID == "" {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "id cannot be empty"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if param.KeyType == "" {
+		param.KeyType = KeyTypeEd25519
+	}
+
+	if err := validatePublicKey(param.KeyType, param.PublicKey); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if a.state.db.Has(revokedKey(param.PublicKey)) {
+		res.Code = code.CodeTypeRevokedKey
+		res.Log = "public key has been revoked"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if a.state.db.Has(identityKey(param.ID)) || a.state.db.Has(identityPublicKeyKey(param.PublicKey)) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = "identity or public key already registered"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	identity := Identity{
+		ID:          param.ID,
+		PublicKey:   param.PublicKey,
+		KeyType:     param.KeyType,
+		Profile:     param.Profile,
+		SinceHeight: a.state.Height,
+		History:     make([]KeyRecord, 0),
+	}
+
+	if err := a.setIdentity(&identity); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal identity"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// checkRotateKeySignature verify that RotateKey is signed by
+// the identity's current key or by sitcompetence
+func (a *SitcomApplication) checkRotateKeySignature(payload *protoTm.Payload, signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	var param IdentityKeyParam
+	if err := json.Unmarshal(payload.Params, &param); err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal params"
+	}
+
+	identity, err := a.getIdentity(param.ID)
+	if err != nil {
+		return nil, code.CodeTypeUnauthorized, err.Error()
+	}
+
+	for _, sig := range txObj.Signatures {
+		if bytes.Equal(sig.PublicKey, identity.PublicKey) && !a.isRevoked(identity.PublicKey) && verifySignature(identity.KeyType, identity.PublicKey, signBytes, sig.Signature) {
+			return [][]byte{identity.PublicKey}, code.CodeTypeOK, ""
+		}
+	}
+
+	return a.checkAdminSignature(signBytes, txObj)
+}
+
+func (a *SitcomApplication) rotateKey(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var param IdentityKeyParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	identity, err := a.getIdentity(param.ID)
+	if err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if param.KeyType == "" {
+		param.KeyType = KeyTypeEd25519
+	}
+
+	if err := validatePublicKey(param.KeyType, param.PublicKey); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if a.state.db.Has(revokedKey(param.PublicKey)) {
+		res.Code = code.CodeTypeRevokedKey
+		res.Log = "public key has been revoked"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if a.state.db.Has(identityPublicKeyKey(param.PublicKey)) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = "public key already registered"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	oldKey := identity.PublicKey
+	identity.History = append(identity.History, KeyRecord{
+		PublicKey:  identity.PublicKey,
+		KeyType:    identity.KeyType,
+		FromHeight: identity.SinceHeight,
+		ToHeight:   a.state.Height,
+	})
+	identity.PublicKey = param.PublicKey
+	identity.KeyType = param.KeyType
+	if param.Profile != nil {
+		identity.Profile = param.Profile
+	}
+	identity.SinceHeight = a.state.Height
+
+	if err := a.setIdentity(identity); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal identity"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := a.moveKeyRights(oldKey, param.PublicKey); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when moving roles and multisig accounts"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// moveKeyRights move the sitcompetence key, roles and multisig memberships
+// from the old key to the new key
+func (a *SitcomApplication) moveKeyRights(oldKey, newKey []byte) error {
+	if string(a.state.db.Get(adminKey)) == base64.StdEncoding.EncodeToString(oldKey) {
+		a.state.db.Set(adminKey, []byte(base64.StdEncoding.EncodeToString(newKey)))
+	}
+
+	for role := range roleList {
+		if a.hasRole(role, oldKey) {
+			a.state.db.Delete(roleKey(role, oldKey))
+			a.state.db.Set(roleKey(role, newKey), []byte{})
+		}
+	}
+
+	updated := make([]*MultisigAccount, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(MultisigPrefix))
+	for ; itr.Valid(); itr.Next() {
+		var account MultisigAccount
+		if err := json.Unmarshal(itr.Value(), &account); err != nil {
+			itr.Close()
+			return err
+		}
+
+		for i, publicKey := range account.PublicKeys {
+			if bytes.Equal(publicKey, oldKey) {
+				account.PublicKeys[i] = newKey
+				updated = append(updated, &account)
+				break
+			}
+		}
+	}
+	itr.Close()
+
+	for _, account := range updated {
+		value, err := json.Marshal(account)
+		if err != nil {
+			return err
+		}
+		a.state.db.Set(multisigKey(account.Name), value)
+	}
+
+	return nil
+}
+
+// withLocation run fn with the location of the payload being delivered inside
+// its transaction, the index of an item at each level of batches and messages
+func (a *SitcomApplication) withLocation(location []int, fn func()) {
+	saved := a.location
+	a.location = location
+	defer func() { a.location = saved }()
+	fn()
+}
+
+// itemLocation return the location of an item of the payload being delivered
+func (a *SitcomApplication) itemLocation(index int) []int {
+	return append(append([]int{}, a.location...), index)
+}
+
+// recordIssuance keep the height of a badge and the hash of the transaction
+// which gave it with the location of the badge's payload inside it, the
+// transaction is kept once for all badges it gave
+func (a *SitcomApplication) recordIssuance(badgeKey []byte, txObj *protoTm.Tx, signers [][]byte) error {
+	txBytes, err := proto.Marshal(txObj)
+	if err != nil {
+		return err
+	}
+
+	txHash := credential.TxHash(txBytes)
+	if !a.state.db.Has(issuanceTxKey(txHash)) {
+		a.state.db.Set(issuanceTxKey(txHash), txBytes)
+	}
+
+	value, err := json.Marshal(BadgeIssuance{
+		Height:   a.state.Height,
+		Time:     a.state.BlockTime,
+		Signers:  signers,
+		TxHash:   txHash,
+		Location: a.location,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(issuanceKey(badgeKey), value)
+	return nil
+}
+
+// getIssuance return the issuance of a badge with the transaction which gave it
+func (a *SitcomApplication) getIssuance(badgeKey []byte) (*BadgeIssuance, *protoTm.Tx, error) {
+	value := a.state.db.Get(issuanceKey(badgeKey))
+	if value == nil {
+		return nil, nil, fmt.Errorf("issuance of %s not found", badgeKey)
+	}
+
+	var issuance BadgeIssuance
+	if err := json.Unmarshal(value, &issuance); err != nil {
+		return nil, nil, err
+	}
+
+	txBytes := a.state.db.Get(issuanceTxKey(issuance.TxHash))
+	if txBytes == nil {
+		return nil, nil, fmt.Errorf("transaction %s of %s not found", issuance.TxHash, badgeKey)
+	}
+
+	var txObj protoTm.Tx
+	if err := proto.Unmarshal(txBytes, &txObj); err != nil {
+		return nil, nil, err
+	}
+
+	return &issuance, &txObj, nil
+}
+
+// verifyIssuance check signatures of a badge against keys active at its height
+func (a *SitcomApplication) verifyIssuance(badgeKey []byte) (*BadgeVerification, error) {
+	issuance, txObj, err := a.getIssuance(badgeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signBytes, err := SignBytes(txObj.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := BadgeVerification{
+		BadgeKey:  string(badgeKey),
+		Height:    issuance.Height,
+		Time:      issuance.Time,
+		TxHash:    issuance.TxHash,
+		SignBytes: signBytes,
+		Signers:   make([]SignerStatus, 0, len(issuance.Signers)),
+		Valid:     len(issuance.Signers) > 0,
+	}
+
+	for _, signer := range issuance.Signers {
+		status, identity, err := a.signerStatus(signer, issuance.Height)
+		if err != nil {
+			return nil, err
+		}
+
+		keyType := KeyTypeEd25519
+		if identity != nil {
+			keyType = identity.keyTypeOf(signer)
+		}
+
+		status.Verified = verifySignature(keyType, signer, signBytes, txObj.Signature)
+		for _, sig := range txObj.Signatures {
+			if !status.Verified && bytes.Equal(sig.PublicKey, signer) {
+				status.Verified = verifySignature(sig.KeyType, signer, signBytes, sig.Signature)
+			}
+		}
+
+		if !status.Verified || status.Compromised || (identity != nil && !status.Active) {
+			verification.Valid = false
+		}
+		verification.Signers = append(verification.Signers, status)
+	}
+
+	return &verification, nil
+}
+
+// keyTypeOf return the key type of a current or historical key
+func (identity *Identity) keyTypeOf(publicKey []byte) string {
+	if bytes.Equal(identity.PublicKey, publicKey) {
+		return identity.KeyType
+	}
+
+	for _, record := range identity.History {
+		if bytes.Equal(record.PublicKey, publicKey) {
+			return record.KeyType
+		}
+	}
+
+	return KeyTypeEd25519
+}
+
+func (a *SitcomApplication) queryIdentity(data []byte) (res types.ResponseQuery) {
+	identity, err := a.getIdentity(string(data))
+	if err != nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = identityKey(identity.ID)
+	res.Value, err = json.Marshal(identity)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal identity"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+func (a *SitcomApplication) queryVerifyBadge(data []byte) (res types.ResponseQuery) {
+	verification, err := a.verifyIssuance(data)
+	if err != nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = data
+	res.Value, err = json.Marshal(verification)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal verification"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
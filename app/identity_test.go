@@ -0,0 +1,91 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestRotateKey(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	ta.block(1)
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	oldKey, old := ta.newKey()
+	newKey, current := ta.newKey()
+	ta.must(code.CodeTypeOK, "RegisterIdentity", IdentityKeyParam{ID: "advisor", PublicKey: oldKey}, admin)
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "advisors", Threshold: 1, PublicKeys: [][]byte{oldKey}}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "GiveBadge", CompetenceID: 1, Account: "advisors"}, admin)
+
+	ta.block(2)
+	before := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", before, old)
+
+	ta.block(3)
+	_, stranger := ta.newKey()
+	ta.must(code.CodeTypeUnauthorized, "RotateKey", IdentityKeyParam{ID: "advisor", PublicKey: newKey}, stranger)
+	ta.must(code.CodeTypeDuplicateKey, "RotateKey", IdentityKeyParam{ID: "advisor", PublicKey: oldKey}, old)
+	ta.must(code.CodeTypeOK, "RotateKey", IdentityKeyParam{ID: "advisor", PublicKey: newKey}, old)
+
+	identity, err := ta.app.getIdentity("advisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(identity.PublicKey, newKey) || identity.SinceHeight != 3 {
+		t.Fatalf("expected the new key since height 3, got %+v", identity)
+	}
+	if len(identity.History) != 1 || !bytes.Equal(identity.History[0].PublicKey, oldKey) || identity.History[0].FromHeight != 1 || identity.History[0].ToHeight != 3 {
+		t.Fatalf("expected the old key in history from 1 to 3, got %+v", identity.History)
+	}
+	if res := ta.query("/identity", "advisor"); res.Code != code.CodeTypeOK || res.Log != "exists" {
+		t.Fatalf("expected the identity, got %d: %s", res.Code, res.Log)
+	}
+
+	// the multisig membership moved to the new key
+	ta.must(code.CodeTypeUnauthorized, "GiveBadge", GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}, old)
+	after := GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", after, current)
+
+	// a badge stays valid when signed by the key active at its height
+	for _, badge := range []GiveBadge{before, after} {
+		verification := ta.verifyBadge(badge)
+		if !verification.Valid || len(verification.Signers) != 1 || verification.Signers[0].Identity != "advisor" {
+			t.Fatalf("expected a valid badge of advisor, got %+v", verification)
+		}
+	}
+}
+
+func TestRotateKeyRejectsRevokedKey(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	publicKey, key := ta.newKey()
+	revoked, _ := ta.newKey()
+	ta.must(code.CodeTypeOK, "RegisterIdentity", IdentityKeyParam{ID: "advisor", PublicKey: publicKey}, admin)
+	ta.must(code.CodeTypeOK, "RevokeKey", KeyRevocation{PublicKey: revoked}, admin)
+
+	ta.must(code.CodeTypeRevokedKey, "RotateKey", IdentityKeyParam{ID: "advisor", PublicKey: revoked}, key)
+}
+
+func TestRotateAdminKey(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.must(code.CodeTypeOK, "RegisterIdentity", IdentityKeyParam{ID: "registrar", PublicKey: admin.Public().(ed25519.PublicKey)}, admin)
+
+	newKey, newAdmin := ta.newKey()
+	ta.must(code.CodeTypeOK, "RotateKey", IdentityKeyParam{ID: "registrar", PublicKey: newKey}, admin)
+
+	// the sitcompetence key moved with the identity's key
+	advisorKey, _ := ta.newKey()
+	ta.must(code.CodeTypeUnauthorized, "AssignRole", AssignRoleParam{PublicKey: advisorKey, Role: RoleAdvisor}, admin)
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: advisorKey, Role: RoleAdvisor}, newAdmin)
+}
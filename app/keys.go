@@ -0,0 +1,37 @@
+package app
+
+import (
+	"bytes"
+
+	"github.com/saguywalker/sitcomchain/protocol"
+)
+
+const (
+	// KeyTypeEd25519 is the default key type
+	KeyTypeEd25519 string = protocol.KeyTypeEd25519
+
+	// KeyTypeSecp256k1 is a compressed secp256k1 key
+	KeyTypeSecp256k1 string = protocol.KeyTypeSecp256k1
+)
+
+// validatePublicKey check that a public key matches its declared type,
+// an empty key type means ed25519
+func validatePublicKey(keyType string, publicKey []byte) error {
+	return protocol.ValidatePublicKey(keyType, publicKey)
+}
+
+// verifySignature verify a signature with an algorithm of the key type
+func verifySignature(keyType string, publicKey, msg, signature []byte) bool {
+	return protocol.VerifySignature(keyType, publicKey, msg, signature)
+}
+
+// containsKey report whether a public key is one of the keys
+func containsKey(keys [][]byte, publicKey []byte) bool {
+	for _, key := range keys {
+		if bytes.Equal(key, publicKey) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+// secp256k1Tx build a transaction of a method signed by a secp256k1 key
+// declared as keyType
+func (ta *testApp) secp256k1Tx(method string, params interface{}, key secp256k1.PrivKeySecp256k1, keyType string) []byte {
+	ta.t.Helper()
+
+	payload := ta.payload(method, params)
+	signBytes, err := SignBytes(payload)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	signature, err := key.Sign(signBytes)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	publicKey := key.PubKey().(secp256k1.PubKeySecp256k1)
+	return ta.encode(&protoTm.Tx{Payload: payload, Signatures: []*protoTm.Signature{{
+		PublicKey: publicKey[:],
+		Signature: signature,
+		KeyType:   keyType,
+	}}})
+}
+
+func TestSecp256k1Signer(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+	key := secp256k1.GenPrivKey()
+	publicKey := key.PubKey().(secp256k1.PubKeySecp256k1)
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: publicKey[:], Role: RoleAdvisor}, admin)
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	if res := ta.deliverTx(ta.secp256k1Tx("ProposeBadge", badge, key, KeyTypeEd25519)); res.Code != code.CodeTypeUnauthorized {
+		t.Fatalf("secp256k1 signature declared as ed25519: expected code %d, got %d: %s", code.CodeTypeUnauthorized, res.Code, res.Log)
+	}
+
+	if res := ta.deliverTx(ta.secp256k1Tx("ProposeBadge", badge, key, KeyTypeSecp256k1)); res.Code != code.CodeTypeOK {
+		t.Fatalf("secp256k1 signature: expected code %d, got %d: %s", code.CodeTypeOK, res.Code, res.Log)
+	}
+
+	if _, err := ta.app.getProposal(1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecp256k1ValidatorRejected(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	publicKey := secp256k1.GenPrivKey().PubKey().(secp256k1.PubKeySecp256k1)
+	validator := SetValidatorParam{PublicKey: publicKey[:], KeyType: KeyTypeSecp256k1, Power: 10}
+
+	tx := ta.tx("SetValidator", validator, admin)
+	if res := ta.app.CheckTx(types.RequestCheckTx{Tx: tx}); res.Code != code.CodeTypeInvalidParams {
+		t.Fatalf("CheckTx of a secp256k1 validator: expected code %d, got %d: %s", code.CodeTypeInvalidParams, res.Code, res.Log)
+	}
+
+	ta.block(1)
+	if res := ta.app.DeliverTx(types.RequestDeliverTx{Tx: tx}); res.Code != code.CodeTypeInvalidParams {
+		t.Fatalf("DeliverTx of a secp256k1 validator: expected code %d, got %d: %s", code.CodeTypeInvalidParams, res.Code, res.Log)
+	}
+	if updates := ta.app.EndBlock(types.RequestEndBlock{Height: 1}).ValidatorUpdates; len(updates) != 0 {
+		t.Fatalf("secp256k1 validator reached EndBlock: %v", updates)
+	}
+
+	edKey, _ := ta.newKey()
+	ta.block(2)
+	ta.must(code.CodeTypeOK, "SetValidator", SetValidatorParam{PublicKey: edKey, Power: 10}, admin)
+	if updates := ta.app.EndBlock(types.RequestEndBlock{Height: 2}).ValidatorUpdates; len(updates) != 1 {
+		t.Fatalf("expected one ed25519 validator update, got %v", updates)
+	}
+}
+
+func TestSecp256k1ValidatorAllowedByConsensusParams(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	ta.app.InitChain(types.RequestInitChain{
+		ChainId: testChainID,
+		ConsensusParams: &types.ConsensusParams{
+			Validator: &types.ValidatorParams{PubKeyTypes: []string{KeyTypeEd25519, KeyTypeSecp256k1}},
+		},
+	})
+
+	admin := ta.admin()
+	publicKey := secp256k1.GenPrivKey().PubKey().(secp256k1.PubKeySecp256k1)
+	ta.must(code.CodeTypeOK, "SetValidator", SetValidatorParam{PublicKey: publicKey[:], KeyType: KeyTypeSecp256k1, Power: 10}, admin)
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/protocol"
+)
+
+const (
+	// MultiMessageMethod is the method of a transaction whose params are
+	// protobuf encoded Messages executed in order
+	MultiMessageMethod string = protocol.MultiMessageMethod
+
+	// MaxMessages is the maximum number of messages in a transaction
+	MaxMessages = 50
+)
+
+func decodeMessages(params []byte) ([]*protoTm.Message, uint32, string) {
+	var messages protoTm.Messages
+	if err := proto.Unmarshal(params, &messages); err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal messages"
+	}
+
+	if len(messages.Messages) == 0 || len(messages.Messages) > MaxMessages {
+		return nil, code.CodeTypeInvalidParams, fmt.Sprintf("a transaction needs 1 to %d messages", MaxMessages)
+	}
+
+	for i, msg := range messages.Messages {
+		if msg.Payload == nil || !methodList[msg.Payload.Method] || msg.Payload.Method == MultiMessageMethod {
+			return nil, code.CodeTypeInvalidMethod, fmt.Sprintf("message %d has an invalid method", i)
+		}
+	}
+
+	return messages.Messages, code.CodeTypeOK, ""
+}
+
+// MessagePayload return the payload the signatures of a message sign, the
+// message's payload with its nonce bound to the nonce of its transaction and
+// to its index, so a signed message cannot be lifted out of its transaction,
+// moved to another place or transaction, nor a transaction's payload signed
+// alone be put into one as a message
+func MessagePayload(txPayload *protoTm.Payload, index int, msg *protoTm.Payload) *protoTm.Payload {
+	nonce := []byte(fmt.Sprintf("%d:", len(txPayload.Nonce)))
+	nonce = append(nonce, txPayload.Nonce...)
+	nonce = append(nonce, fmt.Sprintf(":%d:", index)...)
+	nonce = append(nonce, msg.Nonce...)
+
+	return &protoTm.Payload{
+		Method:  msg.Method,
+		Params:  msg.Params,
+		ChainId: msg.ChainId,
+		Nonce:   nonce,
+		Version: msg.Version,
+	}
+}
+
+// checkMessagesSignature verify every signature of a multi-message transaction,
+// the signatures of the transaction over its sign bytes and the signatures of
+// a message over the sign bytes of its MessagePayload. Which signer may send
+// each message depends on the messages before it, so it is checked in DeliverTx.
+func (a *SitcomApplication) checkMessagesSignature(payload *protoTm.Payload, signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	messages, resCode, resLog := decodeMessages(payload.Params)
+	if resCode != code.CodeTypeOK {
+		return nil, resCode, resLog
+	}
+
+	for i, msg := range messages {
+		if len(msg.Signatures) == 0 {
+			continue
+		}
+
+		msgSignBytes, err := SignBytes(MessagePayload(payload, i, msg.Payload))
+		if err != nil {
+			return nil, code.CodeTypeEncodingError, "error when encoding sign bytes"
+		}
+
+		for _, sig := range msg.Signatures {
+			if !verifySignature(sig.KeyType, sig.PublicKey, msgSignBytes, sig.Signature) {
+				return nil, code.CodeTypeUnauthorized, fmt.Sprintf("message %d: failed in signature verification", i)
+			}
+		}
+	}
+
+	signers := make([][]byte, 0, len(txObj.Signatures))
+	for _, sig := range txObj.Signatures {
+		if !verifySignature(sig.KeyType, sig.PublicKey, signBytes, sig.Signature) {
+			return nil, code.CodeTypeUnauthorized, "failed in signature verification"
+		}
+		signers = append(signers, sig.PublicKey)
+	}
+
+	return signers, code.CodeTypeOK, ""
+}
+
+// messageEvents tag events of a message with its index
+func messageEvents(index int, method string, events []types.Event) []types.Event {
+	tag := cmn.KVPair{Key: []byte("message"), Value: []byte(strconv.Itoa(index))}
+
+	tagged := []types.Event{{
+		Type: "message",
+		Attributes: []cmn.KVPair{
+			tag,
+			{Key: []byte("method"), Value: []byte(method)},
+		},
+	}}
+	for _, event := range events {
+		event.Attributes = append([]cmn.KVPair{tag}, event.Attributes...)
+		tagged = append(tagged, event)
+	}
+
+	return tagged
+}
+
+// deliverMessages execute messages in order and roll them back together when
+// one fails. A message with signatures of its own is verified like a
+// transaction of its MessagePayload, otherwise it is authorized by the
+// signatures of the transaction. The response data lists the result of
+// each message executed and the events of a message carry its index.
+func (a *SitcomApplication) deliverMessages(payload *protoTm.Payload, txObj *protoTm.Tx) (res types.ResponseDeliverTx, err error) {
+	messages, resCode, resLog := decodeMessages(payload.Params)
+	if resCode != code.CodeTypeOK {
+		res.Code = resCode
+		res.Log = resLog
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	signBytes, err := SignBytes(payload)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when encoding sign bytes"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	results := make([]MessageResult, 0, len(messages))
+	events := make([]types.Event, 0)
+	err = a.atomic(func() error {
+		for i, msg := range messages {
+			method := msg.Payload.Method
+			msgTx := txObj
+
+			var signers [][]byte
+			resCode, resLog := uint32(code.CodeTypeOK), ""
+			if len(msg.Signatures) > 0 {
+				msgTx = &protoTm.Tx{Payload: MessagePayload(payload, i, msg.Payload), Signatures: msg.Signatures}
+				if signers, resCode, resLog = a.verifyTx(msgTx); resCode == code.CodeTypeOK {
+					a.useNonce(msgTx.Payload)
+				}
+			} else if signedMethods[method] {
+				if signers, resCode, resLog = a.checkSignatures(msg.Payload, signBytes, txObj); resCode == code.CodeTypeOK {
+					resCode, resLog = a.checkRevokedSigners(signers)
+				}
+			}
+
+			// a message with signatures is delivered as a transaction of its own
+			location := a.itemLocation(i)
+			if msgTx != txObj {
+				location = nil
+			}
+
+			if resCode == code.CodeTypeOK {
+				var msgRes types.ResponseDeliverTx
+				a.withLocation(location, func() {
+					msgRes, _ = a.deliverPayload(msg.Payload, msgTx, signers)
+				})
+				resCode, resLog = msgRes.Code, msgRes.Log
+				results = append(results, MessageResult{Index: i, Method: method, Code: msgRes.Code, Log: msgRes.Log, Data: msgRes.Data})
+				events = append(events, messageEvents(i, method, msgRes.Events)...)
+			} else {
+				results = append(results, MessageResult{Index: i, Method: method, Code: resCode, Log: resLog})
+			}
+
+			if resCode != code.CodeTypeOK {
+				res.Code = resCode
+				res.Log = fmt.Sprintf("message %d: %s", i, resLog)
+				return errors.New(res.Log)
+			}
+		}
+
+		return nil
+	})
+
+	data, marshalErr := json.Marshal(results)
+	if marshalErr != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal results"
+		a.logger.Infoln(res.Log)
+		return res, marshalErr
+	}
+	res.Data = data
+
+	if err != nil {
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	res.Events = events
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
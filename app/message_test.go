@@ -0,0 +1,154 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+// messages encode messages as the params of a multi-message transaction
+func (ta *testApp) messages(messages ...*protoTm.Message) string {
+	ta.t.Helper()
+
+	encoded, err := proto.Marshal(&protoTm.Messages{Messages: messages})
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return string(encoded)
+}
+
+// message build a message authorized by the signatures of its transaction
+func (ta *testApp) message(method string, params interface{}) *protoTm.Message {
+	ta.t.Helper()
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return &protoTm.Message{Payload: &protoTm.Payload{Method: method, Params: encoded}}
+}
+
+func TestMultiMessageRollsBackOnFailedMessage(t *testing.T) {
+	ta, _, organizer := newActivityApp(t)
+	defer ta.close()
+
+	messages := []*protoTm.Message{
+		ta.message("CreateActivity", Activity{ID: 1, Title: "Hackathon", Capacity: 10}),
+		ta.message("SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}),
+		ta.message("ApproveActivity", ApproveActivity{StudentID: ta.student("1"), ActivityID: 1}),
+		ta.message("ApproveActivity", ApproveActivity{StudentID: "not a commitment", ActivityID: 1}),
+	}
+
+	res := ta.deliver(MultiMessageMethod, ta.messages(messages...), organizer)
+	if res.Code == code.CodeTypeOK {
+		t.Fatal("multi-message with a failed message succeeded")
+	}
+
+	var results []MessageResult
+	if err := json.Unmarshal(res.Data, &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[3].Code == code.CodeTypeOK {
+		t.Fatalf("expected message 3 to fail, got %+v", results)
+	}
+	if ta.app.state.db.Has(activityKey(1)) {
+		t.Fatal("activity of a failed multi-message is kept")
+	}
+
+	ta.must(code.CodeTypeOK, MultiMessageMethod, ta.messages(messages[:3]...), organizer)
+	activity, err := ta.app.getActivity(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activity.State != ActivityOpen {
+		t.Fatalf("expected activity %s, got %s", ActivityOpen, activity.State)
+	}
+}
+
+func TestMultiMessageSignatures(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	// the organizer cannot authorize a method of sitcompetence
+	competence := ta.message("CreateCompetence", Competence{ID: 1, Name: "Teamwork"})
+	ta.must(code.CodeTypeUnauthorized, MultiMessageMethod, ta.messages(competence), organizer)
+
+	// a message signed by sitcompetence is verified on its own, over its
+	// payload bound to the transaction and its index
+	competence.Payload.ChainId = testChainID
+	competence.Payload.Version = SignVersion
+	activity := ta.message("CreateActivity", Activity{ID: 1, Title: "Hackathon", Competences: []uint32{1}})
+
+	payload := ta.payload(MultiMessageMethod, "")
+	competence.Signatures = ta.sign(MessagePayload(payload, 0, competence.Payload), admin)
+	payload.Params = []byte(ta.messages(competence, activity))
+	if res := ta.deliverTx(ta.encode(&protoTm.Tx{Payload: payload, Signatures: ta.sign(payload, organizer)})); res.Code != code.CodeTypeOK {
+		t.Fatalf("expected code %d, got %d: %s", code.CodeTypeOK, res.Code, res.Log)
+	}
+	if _, err := ta.app.getCompetence(1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiMessageSignatureIsBound(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	competence := ta.message("CreateCompetence", Competence{ID: 1, Name: "Teamwork"})
+	competence.Payload.ChainId = testChainID
+	competence.Payload.Version = SignVersion
+	competence.Payload.Nonce = []byte("competence-1")
+	activity := ta.message("CreateActivity", Activity{ID: 1, Title: "Hackathon"})
+
+	payload := ta.payload(MultiMessageMethod, "")
+	competence.Signatures = ta.sign(MessagePayload(payload, 1, competence.Payload), admin)
+
+	// lifted out of its transaction
+	lifted := ta.encode(&protoTm.Tx{Payload: competence.Payload, Signatures: competence.Signatures})
+	if res := ta.deliverTx(lifted); res.Code != code.CodeTypeUnauthorized {
+		t.Fatalf("lifted message: expected code %d, got %d: %s", code.CodeTypeUnauthorized, res.Code, res.Log)
+	}
+
+	// moved into another transaction, at the same index or another
+	ta.must(code.CodeTypeUnauthorized, MultiMessageMethod, ta.messages(competence, activity), organizer)
+	ta.must(code.CodeTypeUnauthorized, MultiMessageMethod, ta.messages(activity, competence), organizer)
+
+	// a payload signed alone and put into a transaction
+	alone := &protoTm.Message{Payload: competence.Payload, Signatures: ta.sign(competence.Payload, admin)}
+	ta.must(code.CodeTypeUnauthorized, MultiMessageMethod, ta.messages(activity, alone), organizer)
+
+	if ta.app.state.db.Has(competenceKey(1)) {
+		t.Fatal("competence of a rejected message is kept")
+	}
+
+	payload.Params = []byte(ta.messages(activity, competence))
+	if res := ta.deliverTx(ta.encode(&protoTm.Tx{Payload: payload, Signatures: ta.sign(payload, organizer)})); res.Code != code.CodeTypeOK {
+		t.Fatalf("expected code %d, got %d: %s", code.CodeTypeOK, res.Code, res.Log)
+	}
+}
+
+func TestFailedTxLeavesNoWrites(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	// the portfolio cannot be read, so giveBadge fails after writing the badge
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.app.state.db.Set(portfolioKey(badge.StudentID), []byte("not a list"))
+
+	ta.must(code.CodeTypeEncodingError, "GiveBadge", badge, admin)
+	if ta.hasBadge(badge) {
+		t.Fatal("badge of a failed transaction is kept")
+	}
+	if ta.app.state.db.Has(studentBadgeKey(&badge)) || ta.app.state.db.Has(semesterBadgeKey(&badge)) {
+		t.Fatal("badge indexes of a failed transaction are kept")
+	}
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// MultisigPrefix define the prefix of multisig account keys
+	MultisigPrefix string = "multisig:"
+
+	// SignPolicyPrefix define the prefix of sign policy keys
+	SignPolicyPrefix string = "policy:"
+)
+
+func multisigKey(name string) []byte {
+	return []byte(MultisigPrefix + name)
+}
+
+func signPolicyKey(method string, competenceID uint32) []byte {
+	if competenceID == 0 {
+		return []byte(SignPolicyPrefix + method)
+	}
+	return []byte(fmt.Sprintf("%s%s:%d", SignPolicyPrefix, method, competenceID))
+}
+
+func (a *SitcomApplication) getMultisig(name string) (*MultisigAccount, error) {
+	value := a.state.db.Get(multisigKey(name))
+	if value == nil {
+		return nil, fmt.Errorf("multisig account %s not found", name)
+	}
+
+	var account MultisigAccount
+	if err := json.Unmarshal(value, &account); err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// signPolicy return a sign policy for a payload, a policy for a competence
+// takes precedence over a policy for the whole method
+func (a *SitcomApplication) signPolicy(payload *protoTm.Payload) (*SignPolicy, error) {
+	keys := make([][]byte, 0, 2)
+	if payload.Method == "GiveBadge" {
+		var badge GiveBadge
+		if err := json.Unmarshal(payload.Params, &badge); err == nil && badge.CompetenceID != 0 {
+			keys = append(keys, signPolicyKey(payload.Method, badge.CompetenceID))
+		}
+	}
+	keys = append(keys, signPolicyKey(payload.Method, 0))
+
+	for _, key := range keys {
+		value := a.state.db.Get(key)
+		if value == nil {
+			continue
+		}
+
+		var policy SignPolicy
+		if err := json.Unmarshal(value, &policy); err != nil {
+			return nil, err
+		}
+
+		return &policy, nil
+	}
+
+	return nil, nil
+}
+
+// checkMultisig count signatures from distinct unrevoked keys of the account
+func (a *SitcomApplication) checkMultisig(account *MultisigAccount, signBytes []byte, signatures []*protoTm.Signature) ([][]byte, uint32, string) {
+	members := make(map[string]bool, len(account.PublicKeys))
+	for _, publicKey := range account.PublicKeys {
+		members[string(publicKey)] = true
+	}
+
+	signed := make(map[string]bool)
+	signers := make([][]byte, 0, account.Threshold)
+	for _, sig := range signatures {
+		publicKey := string(sig.PublicKey)
+		if !members[publicKey] || signed[publicKey] || a.isRevoked(sig.PublicKey) {
+			continue
+		}
+		if verifySignature(sig.KeyType, sig.PublicKey, signBytes, sig.Signature) {
+			signed[publicKey] = true
+			signers = append(signers, sig.PublicKey)
+		}
+	}
+
+	if len(signers) < account.Threshold {
+		return nil, code.CodeTypeUnauthorized, fmt.Sprintf("%s requires %d of %d signatures, got %d", account.Name, account.Threshold, len(account.PublicKeys), len(signers))
+	}
+
+	return signers, code.CodeTypeOK, ""
+}
+
+func (a *SitcomApplication) createMultisig(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var account MultisigAccount
+	if err := json.Unmarshal(payload, &account); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if account.Name == "" || account.Threshold < 1 || account.Threshold > len(account.PublicKeys) {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "multisig account needs a name and 1 <= threshold <= number of public keys"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	seen := make(map[string]bool, len(account.PublicKeys))
+	for _, publicKey := range account.PublicKeys {
+		if validatePublicKey(KeyTypeEd25519, publicKey) != nil && validatePublicKey(KeyTypeSecp256k1, publicKey) != nil {
+			res.Code = code.CodeTypeInvalidParams
+			res.Log = "invalid public key size"
+			a.logger.Infoln(res.Log)
+			return res, errors.New(res.Log)
+		}
+
+		if seen[string(publicKey)] {
+			res.Code = code.CodeTypeInvalidParams
+			res.Log = "duplicate public key in multisig account"
+			a.logger.Infoln(res.Log)
+			return res, errors.New(res.Log)
+		}
+		seen[string(publicKey)] = true
+	}
+
+	value, err := json.Marshal(account)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal multisig account"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.db.Set(multisigKey(account.Name), value)
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) setSignPolicy(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var policy SignPolicy
+	if err := json.Unmarshal(payload, &policy); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if !signedMethods[policy.Method] {
+		res.Code = code.CodeTypeInvalidMethod
+		res.Log = fmt.Sprintf("method %s is not a signed method", policy.Method)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	key := signPolicyKey(policy.Method, policy.CompetenceID)
+	if policy.Account == "" {
+		// Remove policy, method falls back to a single sitcompetence signature
+		a.state.db.Delete(key)
+		a.state.Size++
+		res.Code = code.CodeTypeOK
+		res.Log = "success"
+		return res, nil
+	}
+
+	if _, err := a.getMultisig(policy.Account); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	value, err := json.Marshal(policy)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal sign policy"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.db.Set(key, value)
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
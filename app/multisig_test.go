@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestMultisigThreshold(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	publicKeys := make([][]byte, 3)
+	keys := make([]ed25519.PrivateKey, 3)
+	for i := range keys {
+		publicKeys[i], keys[i] = ta.newKey()
+	}
+
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "committee", Threshold: 2, PublicKeys: publicKeys}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "GiveBadge", CompetenceID: 1, Account: "committee"}, admin)
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeUnauthorized, "GiveBadge", badge, admin)
+	ta.must(code.CodeTypeUnauthorized, "GiveBadge", badge, keys[0])
+	ta.must(code.CodeTypeUnauthorized, "GiveBadge", badge, keys[0], keys[0])
+	if ta.hasBadge(badge) {
+		t.Fatal("badge given below the threshold")
+	}
+
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, keys[0], keys[2])
+	if !ta.hasBadge(badge) {
+		t.Fatal("badge not given at the threshold")
+	}
+}
+
+func TestMultisigRejectsDuplicateKeys(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	publicKey, _ := ta.newKey()
+
+	account := MultisigAccount{Name: "committee", Threshold: 2, PublicKeys: [][]byte{publicKey, publicKey}}
+	ta.must(code.CodeTypeInvalidParams, "CreateMultisig", account, admin)
+}
+
+func TestMultisigThresholdAboveMembers(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	publicKey, _ := ta.newKey()
+
+	account := MultisigAccount{Name: "committee", Threshold: 2, PublicKeys: [][]byte{publicKey}}
+	ta.must(code.CodeTypeInvalidParams, "CreateMultisig", account, admin)
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/credential"
+)
+
+func TestOpenBadges(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	profile := &IssuerProfile{Name: "School of Information Technology", URL: "https://sit.example.com"}
+	ta.must(code.CodeTypeOK, "RegisterIdentity", IdentityKeyParam{ID: "sit", PublicKey: admin.Public().(ed25519.PublicKey), Profile: profile}, admin)
+
+	imageHash := strings.Repeat("ab", 32)
+	ta.catalog(admin, Competence{
+		ID:              1,
+		Name:            "Teamwork",
+		Description:     "Works well in a team",
+		AchievementType: "Competency",
+		Criteria:        "Led a team project",
+		ImageHash:       imageHash,
+	})
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1, Narrative: "led the hackathon team"}
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+
+	var assertions []credential.OpenBadgeCredential
+	ta.queryJSON("/openbadges", ta.studentProof("1"), &assertions)
+	if len(assertions) != 1 {
+		t.Fatalf("expected 1 assertion, got %+v", assertions)
+	}
+	assertion := assertions[0]
+
+	vc, _ := ta.credential(badge)
+	if assertion.ID != vc.ID || assertion.IssuanceDate != vc.IssuanceDate || !reflect.DeepEqual(assertion.Proof, vc.Proof) {
+		t.Fatalf("assertion does not keep the credential's id, date and proof: %+v", assertion)
+	}
+	if !reflect.DeepEqual(assertion.Context, []string{credential.ContextV1, credential.ContextOpenBadgesV3}) || assertion.Type[1] != credential.TypeOpenBadgeCredential {
+		t.Fatalf("unexpected context %v and type %v", assertion.Context, assertion.Type)
+	}
+	if assertion.Issuer.ID != vc.Issuer || assertion.Issuer.Name != profile.Name || assertion.Issuer.URL != profile.URL {
+		t.Fatalf("expected the issuer's profile, got %+v", assertion.Issuer)
+	}
+
+	achievement := assertion.CredentialSubject.Achievement
+	if achievement.ID != credential.AchievementID(testChainID, 1) || achievement.Name != "Teamwork" || achievement.AchievementType != "Competency" || achievement.Criteria.Narrative != "Led a team project" {
+		t.Fatalf("unexpected achievement %+v", achievement)
+	}
+	if achievement.Image == nil || achievement.Image.ID != "urn:sha256:"+imageHash {
+		t.Fatalf("expected the image by its hash, got %+v", achievement.Image)
+	}
+	if assertion.CredentialSubject.Narrative != badge.Narrative {
+		t.Fatalf("expected the badge's narrative, got %q", assertion.CredentialSubject.Narrative)
+	}
+
+	if res := ta.query("/openbadges", ta.studentProof("2")); res.Log != "does not exist" {
+		t.Fatalf("expected no assertion of student 2, got %s", res.Log)
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/disclosure"
+)
+
+const (
+	// PortfolioPrefix define the prefix of a student's badge keys in issuance order
+	PortfolioPrefix string = "portfolio:"
+
+	// PortfolioLeafPrefix define the prefix of a student's salted portfolio leaves
+	PortfolioLeafPrefix string = "portfolioleaf:"
+)
+
+func portfolioKey(studentID string) []byte {
+	return []byte(PortfolioPrefix + studentID)
+}
+
+func portfolioLeafKey(studentID string) []byte {
+	return []byte(PortfolioLeafPrefix + studentID)
+}
+
+func (a *SitcomApplication) getList(key []byte) ([]string, error) {
+	items := make([]string, 0)
+	value := a.state.db.Get(key)
+	if value == nil {
+		return items, nil
+	}
+
+	if err := json.Unmarshal(value, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (a *SitcomApplication) appendList(key []byte, item string) error {
+	items, err := a.getList(key)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(append(items, item))
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(key, value)
+	return nil
+}
+
+// getPortfolio return a student's badge keys in issuance order
+func (a *SitcomApplication) getPortfolio(studentID string) ([]string, error) {
+	return a.getList(portfolioKey(studentID))
+}
+
+// appendPortfolio add a badge to the end of the student's portfolio, and its
+// salted leaf, when given, to the leaves whose root is disclosed. Leaves only
+// grow so the root of the first n leaves never changes.
+func (a *SitcomApplication) appendPortfolio(badge *GiveBadge, badgeKey []byte) error {
+	if err := a.appendList(portfolioKey(badge.StudentID), string(badgeKey)); err != nil {
+		return err
+	}
+
+	if badge.PortfolioLeaf == "" {
+		return nil
+	}
+
+	return a.appendList(portfolioLeafKey(badge.StudentID), badge.PortfolioLeaf)
+}
+
+// isSha256Hex check that value is a sha256 in lowercase hex
+func isSha256Hex(value string) bool {
+	hashed, err := hex.DecodeString(value)
+	return err == nil && len(hashed) == sha256.Size && value == strings.ToLower(value)
+}
+
+// validatePortfolioLeaf check that a portfolio leaf is empty or a sha256 in lowercase hex
+func validatePortfolioLeaf(leaf string) error {
+	if leaf != "" && !isSha256Hex(leaf) {
+		return errors.New("portfolio_leaf must be a sha256 in lowercase hex, see disclosure.LeafHex")
+	}
+
+	return nil
+}
+
+// queryPortfolio return the root of a student's portfolio leaves, data is
+// the student ID optionally followed by ":" and a size to return the root
+// at that size
+func (a *SitcomApplication) queryPortfolio(data []byte) (res types.ResponseQuery) {
+	parts := bytes.SplitN(data, []byte(":"), 2)
+	leaves, err := a.getList(portfolioLeafKey(string(parts[0])))
+	if err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal portfolio"
+		return
+	}
+
+	if len(parts) == 2 {
+		size, err := strconv.Atoi(string(parts[1]))
+		if err != nil || size < 0 || size > len(leaves) {
+			res.Code = code.CodeTypeInvalidParams
+			res.Log = "invalid portfolio size"
+			return
+		}
+		leaves = leaves[:size]
+	}
+
+	if len(leaves) == 0 {
+		res.Log = "does not exist"
+		return
+	}
+
+	items := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		items[i], err = hex.DecodeString(leaf)
+		if err != nil {
+			res.Code = code.CodeTypeDecodingError
+			res.Log = "error when decode portfolio leaf"
+			return
+		}
+	}
+
+	res.Key = portfolioLeafKey(string(parts[0]))
+	res.Value, err = json.Marshal(Portfolio{
+		StudentID: string(parts[0]),
+		Root:      disclosure.Root(items),
+	})
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal portfolio"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/disclosure"
+)
+
+func (ta *testApp) portfolioRoot(data string) []byte {
+	ta.t.Helper()
+
+	res := ta.query("/portfolio", data)
+	if res.Code != code.CodeTypeOK {
+		ta.t.Fatalf("/portfolio %s: %s", data, res.Log)
+	}
+
+	var portfolio Portfolio
+	if err := json.Unmarshal(res.Value, &portfolio); err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return portfolio.Root
+}
+
+func TestPortfolioDisclosure(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"}, Competence{ID: 3, Name: "Ethics"})
+
+	secret := []byte("a secret of the student")
+	studentID := ta.student("1")
+	badgeKeys := make([][]byte, 0)
+	for _, competenceID := range []uint32{1, 2} {
+		badge := GiveBadge{StudentID: studentID, CompetenceID: competenceID, Semester: 1}
+		badgeKey, err := BadgeKey(&badge)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		badge.PortfolioLeaf = disclosure.LeafHex(secret, badgeKey)
+		ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+		badgeKeys = append(badgeKeys, badgeKey)
+	}
+
+	pkg, err := disclosure.New(studentID, secret, badgeKeys, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a later badge does not change the root at the package's size
+	third := GiveBadge{StudentID: studentID, CompetenceID: 3, Semester: 1, PortfolioLeaf: disclosure.LeafHex(secret, []byte("third"))}
+	ta.must(code.CodeTypeOK, "GiveBadge", third, admin)
+
+	if err := pkg.Verify(ta.portfolioRoot(fmt.Sprintf("%s:%d", studentID, pkg.Size()))); err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.Verify(ta.portfolioRoot(studentID)); err == nil {
+		t.Fatal("package verifies against the root of another size")
+	}
+
+	var portfolio map[string]interface{}
+	if err := json.Unmarshal(ta.query("/portfolio", studentID).Value, &portfolio); err != nil {
+		t.Fatal(err)
+	}
+	if len(portfolio) != 2 || portfolio["root"] == nil || portfolio["student_id"] != studentID {
+		t.Fatalf("portfolio reveals more than its root: %v", portfolio)
+	}
+}
+
+func TestPortfolioLeafFormat(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1, PortfolioLeaf: "not a leaf"}
+	ta.must(code.CodeTypeInvalidParams, "GiveBadge", badge, admin)
+
+	badge.PortfolioLeaf = ""
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+	if res := ta.query("/portfolio", badge.StudentID); res.Log != "does not exist" {
+		t.Fatalf("badge without a leaf is in the portfolio: %s", res.Value)
+	}
+}
+
+func TestAutoAwardPortfolioLeaf(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork", AwardRule: &AwardRule{Tag: "volunteer", Count: 1}})
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Beach cleanup", Tags: []string{"volunteer"}, Semester: 1}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	studentID := ta.student("1")
+	badgeKey, err := BadgeKey(&GiveBadge{StudentID: studentID, CompetenceID: 1, Semester: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approval := ApproveActivity{StudentID: studentID, ActivityID: 1, PortfolioLeaves: map[uint32]string{1: "not a leaf"}}
+	ta.must(code.CodeTypeInvalidParams, "ApproveActivity", approval, organizer)
+
+	approval.PortfolioLeaves[1] = disclosure.LeafHex(ta.secret("1"), badgeKey)
+	ta.must(code.CodeTypeOK, "ApproveActivity", approval, organizer)
+	if !ta.app.state.db.Has(badgeKey) {
+		t.Fatal("badge was not awarded")
+	}
+
+	pkg, err := disclosure.New(studentID, ta.secret("1"), [][]byte{badgeKey}, []int{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.Verify(ta.portfolioRoot(studentID)); err != nil {
+		t.Fatal(err)
+	}
+}
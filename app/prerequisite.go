@@ -0,0 +1,135 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// StudentBadgePrefix define the prefix of student to competence and semester index
+	StudentBadgePrefix string = "studentbadge:"
+)
+
+func studentBadgePrefix(studentID string) []byte {
+	return []byte(StudentBadgePrefix + studentID + ":")
+}
+
+func studentCompetencePrefix(studentID string, competenceID uint32) []byte {
+	return append(studentBadgePrefix(studentID), strconv.FormatUint(uint64(competenceID), 10)+":"...)
+}
+
+// studentBadgeKey index a badge by student, competence and semester,
+// so badges of one competence from different semesters are all kept
+func studentBadgeKey(badge *GiveBadge) []byte {
+	return append(studentCompetencePrefix(badge.StudentID, badge.CompetenceID), strconv.FormatUint(uint64(badge.Semester), 10)...)
+}
+
+// hasCompetence report whether a student holds a badge of the competence in any semester
+func (a *SitcomApplication) hasCompetence(studentID string, competenceID uint32) bool {
+	itr := dbm.IteratePrefix(a.state.db, studentCompetencePrefix(studentID, competenceID))
+	defer itr.Close()
+	return itr.Valid()
+}
+
+// missingPrerequisites return prerequisites of a competence which the student does not hold
+func (a *SitcomApplication) missingPrerequisites(studentID string, competence *Competence) []uint32 {
+	missing := make([]uint32, 0)
+	for _, id := range competence.Prerequisites {
+		if !a.hasCompetence(studentID, id) {
+			missing = append(missing, id)
+		}
+	}
+
+	return missing
+}
+
+// validatePrerequisites check that prerequisites exist and do not make a cycle
+func (a *SitcomApplication) validatePrerequisites(id uint32, prerequisites []uint32) error {
+	visited := make(map[uint32]bool)
+	stack := append([]uint32{}, prerequisites...)
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current == id {
+			return fmt.Errorf("prerequisites of competence %d make a cycle", id)
+		}
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		competence, err := a.getCompetence(current)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, competence.Prerequisites...)
+	}
+
+	return nil
+}
+
+// checkPrerequisites return an error code when the student lacks a prerequisite
+// and no key holding the override role co-signed
+func (a *SitcomApplication) checkPrerequisites(badge *GiveBadge, overridden bool) (uint32, string) {
+	competence, err := a.getCompetence(badge.CompetenceID)
+	if err != nil {
+		return code.CodeTypeNotFound, err.Error()
+	}
+
+	missing := a.missingPrerequisites(badge.StudentID, competence)
+	if len(missing) == 0 || overridden {
+		return code.CodeTypeOK, ""
+	}
+
+	return code.CodeTypeMissingPrerequisite, fmt.Sprintf("student %s lacks prerequisites %v of competence %d", badge.StudentID, missing, competence.ID)
+}
+
+// queryProgress return a student's progress through the competence graph,
+// data is a StudentProof
+func (a *SitcomApplication) queryProgress(data []byte) (res types.ResponseQuery) {
+	studentID, resCode, resLog := provenStudent(data)
+	if resCode != code.CodeTypeOK {
+		res.Code = resCode
+		res.Log = resLog
+		return
+	}
+
+	progress := make([]*CompetenceProgress, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(CompetencePrefix))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var competence Competence
+		if err := json.Unmarshal(itr.Value(), &competence); err != nil {
+			continue
+		}
+
+		missing := a.missingPrerequisites(studentID, &competence)
+		progress = append(progress, &CompetenceProgress{
+			ID:            competence.ID,
+			Name:          competence.Name,
+			Level:         competence.Level,
+			Prerequisites: competence.Prerequisites,
+			Earned:        a.hasCompetence(studentID, competence.ID),
+			Missing:       missing,
+			Eligible:      !competence.Deprecated && len(missing) == 0,
+		})
+	}
+
+	value, err := json.Marshal(progress)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal progress"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = value
+	return
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+// progress return a student's progress by competence, id is the plaintext student ID
+func (ta *testApp) progress(id string) map[uint32]*CompetenceProgress {
+	ta.t.Helper()
+
+	var progress []*CompetenceProgress
+	if err := json.Unmarshal(ta.query("/progress", ta.studentProof(id)).Value, &progress); err != nil {
+		ta.t.Fatal(err)
+	}
+
+	byID := make(map[uint32]*CompetenceProgress)
+	for _, item := range progress {
+		byID[item.ID] = item
+	}
+	return byID
+}
+
+func (ta *testApp) countPrefix(prefix []byte) int {
+	count := 0
+	itr := dbm.IteratePrefix(ta.app.state.db, prefix)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	return count
+}
+
+func TestPrerequisites(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin,
+		Competence{ID: 1, Name: "Programming"},
+		Competence{ID: 2, Name: "Algorithms", Prerequisites: []uint32{1}},
+	)
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 2, Name: "2/2019", Start: 0, End: 1 << 40}, admin)
+
+	studentID := ta.student("1")
+	advanced := GiveBadge{StudentID: studentID, CompetenceID: 2, Semester: 2}
+	ta.must(code.CodeTypeMissingPrerequisite, "GiveBadge", advanced, admin)
+	if progress := ta.progress("1")[2]; progress.Eligible || len(progress.Missing) != 1 {
+		t.Fatalf("expected competence 2 to miss competence 1, got %+v", progress)
+	}
+
+	// the same competence in two semesters, neither hides the other
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: studentID, CompetenceID: 1, Semester: 1}, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: studentID, CompetenceID: 1, Semester: 2}, admin)
+	if count := ta.countPrefix(studentCompetencePrefix(studentID, 1)); count != 2 {
+		t.Fatalf("expected 2 indexed badges of competence 1, got %d", count)
+	}
+	if progress := ta.progress("1")[2]; !progress.Eligible {
+		t.Fatalf("expected competence 2 to be eligible, got %+v", progress)
+	}
+
+	ta.must(code.CodeTypeOK, "GiveBadge", advanced, admin)
+	if progress := ta.progress("1")[2]; !progress.Earned {
+		t.Fatalf("expected competence 2 to be earned, got %+v", progress)
+	}
+}
+
+func TestPrerequisiteOverride(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin,
+		Competence{ID: 1, Name: "Programming"},
+		Competence{ID: 2, Name: "Algorithms", Prerequisites: []uint32{1}},
+	)
+
+	overrideKey, override := ta.newKey()
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: overrideKey, Role: RolePrerequisiteOverride}, admin)
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 2, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin, override)
+}
+
+func TestPrerequisiteCycle(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin,
+		Competence{ID: 1, Name: "Programming"},
+		Competence{ID: 2, Name: "Algorithms", Prerequisites: []uint32{1}},
+	)
+
+	ta.must(code.CodeTypeInvalidParams, "UpdateCompetence", Competence{ID: 1, Name: "Programming", Prerequisites: []uint32{2}}, admin)
+	ta.must(code.CodeTypeInvalidParams, "CreateCompetence", Competence{ID: 3, Name: "Compilers", Prerequisites: []uint32{9}}, admin)
+}
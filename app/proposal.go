@@ -0,0 +1,326 @@
+package app
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// ProposalPrefix define the prefix of badge proposal keys
+	ProposalPrefix string = "proposal:"
+
+	// ProposalExpiryBlocks is number of blocks a proposal waits for approval
+	ProposalExpiryBlocks int64 = 1000
+
+	// ProposalPending is a proposal waiting for approval
+	ProposalPending string = "pending"
+
+	// ProposalApproved is a proposal whose badge has been given
+	ProposalApproved string = "approved"
+
+	// ProposalExpired is a proposal which was not approved in time
+	ProposalExpired string = "expired"
+)
+
+var (
+	proposalSeqKey = []byte("proposalSeq")
+)
+
+func proposalKey(id uint64) []byte {
+	return []byte(ProposalPrefix + strconv.FormatUint(id, 10))
+}
+
+func (a *SitcomApplication) nextProposalID() uint64 {
+	var id uint64
+	if value := a.state.db.Get(proposalSeqKey); len(value) == 8 {
+		id = binary.BigEndian.Uint64(value)
+	}
+	id++
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, id)
+	a.state.db.Set(proposalSeqKey, value)
+	return id
+}
+
+func (a *SitcomApplication) getProposal(id uint64) (*BadgeProposal, error) {
+	value := a.state.db.Get(proposalKey(id))
+	if value == nil {
+		return nil, fmt.Errorf("proposal %d not found", id)
+	}
+
+	var proposal BadgeProposal
+	if err := json.Unmarshal(value, &proposal); err != nil {
+		return nil, err
+	}
+
+	if proposal.Status == ProposalPending && a.state.Height > proposal.ExpiresAt {
+		proposal.Status = ProposalExpired
+	}
+
+	return &proposal, nil
+}
+
+func (a *SitcomApplication) setProposal(proposal *BadgeProposal) error {
+	value, err := json.Marshal(proposal)
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(proposalKey(proposal.ID), value)
+	return nil
+}
+
+func (a *SitcomApplication) proposeBadge(payload []byte, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var badge GiveBadge
+	if err := json.Unmarshal(payload, &badge); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res.Code, res.Log = a.checkStudent(badge.StudentID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if res.Code, res.Log = a.checkCompetence(badge.CompetenceID); res.Code != code.CodeTypeOK {
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if err := validateEvidence(badge.Evidence); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if err := validatePortfolioLeaf(badge.PortfolioLeaf); err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if _, err := a.getSemester(badge.Semester); err != nil {
+		res.Code = code.CodeTypeNotFound
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	badgeKey, err := BadgeKey(&badge)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal badgeKey"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if a.state.db.Has(badgeKey) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = fmt.Sprintf("badge %s has already been given", badgeKey)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	proposal := BadgeProposal{
+		ID:        a.nextProposalID(),
+		Badge:     badge,
+		Proposer:  signers[0],
+		Height:    a.state.Height,
+		ExpiresAt: a.state.Height + ProposalExpiryBlocks,
+		Approvals: make([]ProposalApproval, 0),
+		Status:    ProposalPending,
+	}
+
+	if err := a.setProposal(&proposal); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal proposal"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Data = []byte(strconv.FormatUint(proposal.ID, 10))
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// checkApproveProposalSignature verify the signatures required for
+// ApproveProposal and those GiveBadge requires for the proposal's competence,
+// so an approval cannot bypass a per-competence sign policy. A proposer
+// cannot approve its own proposal.
+func (a *SitcomApplication) checkApproveProposalSignature(payload *protoTm.Payload, signBytes []byte, txObj *protoTm.Tx) ([][]byte, uint32, string) {
+	signers, resCode, resLog := a.checkMethodSignature(payload, signBytes, txObj)
+	if resCode != code.CodeTypeOK {
+		return nil, resCode, resLog
+	}
+
+	var param ApproveProposalParam
+	if err := json.Unmarshal(payload.Params, &param); err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal params"
+	}
+
+	proposal, err := a.getProposal(param.ProposalID)
+	if err != nil {
+		// rejected when the transaction is delivered
+		return signers, code.CodeTypeOK, ""
+	}
+
+	params, err := json.Marshal(GiveBadge{CompetenceID: proposal.Badge.CompetenceID})
+	if err != nil {
+		return nil, code.CodeTypeEncodingError, "error when marshal params"
+	}
+
+	policy, err := a.signPolicy(&protoTm.Payload{Method: "GiveBadge", Params: params})
+	if err != nil {
+		return nil, code.CodeTypeUnmarshalError, "error when unmarshal sign policy"
+	}
+
+	if policy != nil {
+		account, err := a.getMultisig(policy.Account)
+		if err != nil {
+			return nil, code.CodeTypeUnauthorized, err.Error()
+		}
+
+		policySigners, resCode, resLog := a.checkMultisig(account, signBytes, txObj.Signatures)
+		if resCode != code.CodeTypeOK {
+			return nil, resCode, fmt.Sprintf("competence %d: %s", proposal.Badge.CompetenceID, resLog)
+		}
+
+		for _, signer := range policySigners {
+			if !containsKey(signers, signer) {
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	if containsKey(signers, proposal.Proposer) {
+		return nil, code.CodeTypeUnauthorized, fmt.Sprintf("proposal %d cannot be approved by its proposer", proposal.ID)
+	}
+
+	return signers, code.CodeTypeOK, ""
+}
+
+func (a *SitcomApplication) approveProposal(payload []byte, txObj *protoTm.Tx, signers [][]byte) (res types.ResponseDeliverTx, err error) {
+	var param ApproveProposalParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	proposal, err := a.getProposal(param.ProposalID)
+	if err != nil {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = err.Error()
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if proposal.Status != ProposalPending {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = fmt.Sprintf("proposal %d is %s", proposal.ID, proposal.Status)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	badgeParams, err := json.Marshal(proposal.Badge)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal badge"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if res, err = a.giveBadge(badgeParams, txObj, signers); err != nil {
+		return res, err
+	}
+
+	proposal.Approvals = append(proposal.Approvals, ProposalApproval{
+		Approver: signers[0],
+		Height:   a.state.Height,
+	})
+	proposal.Status = ProposalApproved
+
+	if err := a.setProposal(proposal); err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal proposal"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (a *SitcomApplication) queryProposal(data []byte) (res types.ResponseQuery) {
+	id, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid proposal id"
+		return
+	}
+
+	proposal, err := a.getProposal(id)
+	if err != nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = proposalKey(id)
+	res.Value, err = json.Marshal(proposal)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal proposal"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+func (a *SitcomApplication) queryProposals(status []byte) (res types.ResponseQuery) {
+	proposals := make([]*BadgeProposal, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(ProposalPrefix))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var proposal BadgeProposal
+		if err := json.Unmarshal(itr.Value(), &proposal); err != nil {
+			continue
+		}
+
+		if proposal.Status == ProposalPending && a.state.Height > proposal.ExpiresAt {
+			proposal.Status = ProposalExpired
+		}
+
+		if len(status) == 0 || proposal.Status == string(status) {
+			proposals = append(proposals, &proposal)
+		}
+	}
+
+	value, err := json.Marshal(proposals)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal proposals"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = value
+	return
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func newProposalApp(t *testing.T) (*testApp, ed25519.PrivateKey, ed25519.PrivateKey) {
+	ta := newTestApp(t)
+	ta.block(1)
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	advisorKey, advisor := ta.newKey()
+	headKey, head := ta.newKey()
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: advisorKey, Role: RoleAdvisor}, admin)
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: headKey, Role: RoleHeadOfDepartment}, admin)
+
+	return ta, advisor, head
+}
+
+func (ta *testApp) proposal(id string) *BadgeProposal {
+	ta.t.Helper()
+
+	var proposal BadgeProposal
+	if err := json.Unmarshal(ta.query("/proposal", id).Value, &proposal); err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return &proposal
+}
+
+func TestProposalApprovedBeforeExpiry(t *testing.T) {
+	ta, advisor, head := newProposalApp(t)
+	defer ta.close()
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "ProposeBadge", badge, advisor)
+	ta.must(code.CodeTypeUnauthorized, "ApproveProposal", ApproveProposalParam{ProposalID: 1}, advisor)
+
+	ta.block(1 + ProposalExpiryBlocks)
+	ta.must(code.CodeTypeOK, "ApproveProposal", ApproveProposalParam{ProposalID: 1}, head)
+
+	if status := ta.proposal("1").Status; status != ProposalApproved {
+		t.Fatalf("expected proposal %s, got %s", ProposalApproved, status)
+	}
+	if !ta.hasBadge(badge) {
+		t.Fatal("badge of the approved proposal not given")
+	}
+}
+
+func TestProposalExpires(t *testing.T) {
+	ta, advisor, head := newProposalApp(t)
+	defer ta.close()
+
+	badge := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "ProposeBadge", badge, advisor)
+	if status := ta.proposal("1").Status; status != ProposalPending {
+		t.Fatalf("expected proposal %s, got %s", ProposalPending, status)
+	}
+
+	ta.block(2 + ProposalExpiryBlocks)
+	if status := ta.proposal("1").Status; status != ProposalExpired {
+		t.Fatalf("expected proposal %s, got %s", ProposalExpired, status)
+	}
+
+	ta.must(code.CodeTypeInvalidParams, "ApproveProposal", ApproveProposalParam{ProposalID: 1}, head)
+	if ta.hasBadge(badge) {
+		t.Fatal("badge of an expired proposal given")
+	}
+}
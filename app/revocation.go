@@ -0,0 +1,122 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// RevokedPrefix define the prefix of revoked key records
+	RevokedPrefix string = "revoked:"
+)
+
+func revokedKey(publicKey []byte) []byte {
+	return []byte(RevokedPrefix + base64.StdEncoding.EncodeToString(publicKey))
+}
+
+func (a *SitcomApplication) getRevocation(publicKey []byte) (*KeyRevocation, error) {
+	value := a.state.db.Get(revokedKey(publicKey))
+	if value == nil {
+		return nil, nil
+	}
+
+	var revocation KeyRevocation
+	if err := json.Unmarshal(value, &revocation); err != nil {
+		return nil, err
+	}
+
+	return &revocation, nil
+}
+
+// isRevoked report whether a key has been revoked, signatures of a revoked
+// key do not count toward a role, an activity or a multisig threshold
+func (a *SitcomApplication) isRevoked(publicKey []byte) bool {
+	return a.state.db.Has(revokedKey(publicKey))
+}
+
+// checkRevokedSigners reject a transaction signed by a revoked key
+func (a *SitcomApplication) checkRevokedSigners(signers [][]byte) (uint32, string) {
+	for _, signer := range signers {
+		if a.isRevoked(signer) {
+			return code.CodeTypeRevokedKey, fmt.Sprintf("key %s has been revoked", base64.StdEncoding.EncodeToString(signer))
+		}
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+func (a *SitcomApplication) revokeKey(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var revocation KeyRevocation
+	if err := json.Unmarshal(payload, &revocation); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if len(revocation.PublicKey) == 0 {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "public key cannot be empty"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if a.state.db.Has(revokedKey(revocation.PublicKey)) {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = "key has already been revoked"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	revocation.Height = a.state.Height
+	if revocation.CompromisedAt == 0 || revocation.CompromisedAt > revocation.Height {
+		revocation.CompromisedAt = revocation.Height
+	}
+
+	value, err := json.Marshal(revocation)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal revocation"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.db.Set(revokedKey(revocation.PublicKey), value)
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) queryRevoked(data []byte) (res types.ResponseQuery) {
+	publicKey, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid public key"
+		return
+	}
+
+	revocation, err := a.getRevocation(publicKey)
+	if err != nil || revocation == nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = revokedKey(publicKey)
+	res.Value, err = json.Marshal(revocation)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal revocation"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
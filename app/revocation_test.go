@@ -0,0 +1,94 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestRevokedKeyDoesNotCountTowardThreshold(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	publicKeys := make([][]byte, 3)
+	keys := make([]ed25519.PrivateKey, 3)
+	for i := range keys {
+		publicKeys[i], keys[i] = ta.newKey()
+	}
+
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "committee", Threshold: 2, PublicKeys: publicKeys}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "AssignRole", Account: "committee"}, admin)
+	ta.must(code.CodeTypeOK, "RevokeKey", KeyRevocation{PublicKey: publicKeys[0], Reason: "lost laptop"}, admin)
+	ta.must(code.CodeTypeRevokedKey, "RegisterIdentity", IdentityKeyParam{ID: "advisor", PublicKey: publicKeys[0]}, admin)
+
+	ta.must(code.CodeTypeUnauthorized, "AssignRole", AssignRoleParam{PublicKey: publicKeys[1], Role: RoleAdvisor}, keys[0], keys[1])
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: publicKeys[1], Role: RoleAdvisor}, keys[0], keys[1], keys[2])
+}
+
+func TestRevokedAdminKey(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	adminKey := admin.Public().(ed25519.PublicKey)
+	ta.must(code.CodeTypeOK, "RevokeKey", KeyRevocation{PublicKey: adminKey}, admin)
+
+	ta.must(code.CodeTypeRevokedKey, "AssignRole", AssignRoleParam{PublicKey: adminKey, Role: RoleAdvisor}, admin)
+}
+
+func TestRevocationInvalidatesBadgesSinceCompromise(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	ta.block(1)
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	publicKeys := make([][]byte, 2)
+	keys := make([]ed25519.PrivateKey, 2)
+	for i := range keys {
+		publicKeys[i], keys[i] = ta.newKey()
+	}
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "advisors", Threshold: 1, PublicKeys: publicKeys}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "GiveBadge", CompetenceID: 1, Account: "advisors"}, admin)
+
+	ta.block(2)
+	compromised := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	kept := GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", compromised, keys[0])
+	ta.must(code.CodeTypeOK, "GiveBadge", kept, keys[1])
+
+	ta.block(3)
+	ta.must(code.CodeTypeOK, "RevokeKey", KeyRevocation{PublicKey: publicKeys[0], CompromisedAt: 2}, admin)
+	ta.must(code.CodeTypeOK, "RevokeKey", KeyRevocation{PublicKey: publicKeys[1], CompromisedAt: 3}, admin)
+
+	if ta.verifyBadge(compromised).Valid {
+		t.Fatal("badge signed after its key was compromised is valid")
+	}
+	if !ta.verifyBadge(kept).Valid {
+		t.Fatal("badge signed before its key was compromised is invalid")
+	}
+
+	third := GiveBadge{StudentID: ta.student("3"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeUnauthorized, "GiveBadge", third, keys[0])
+}
+
+func (ta *testApp) verifyBadge(badge GiveBadge) *BadgeVerification {
+	ta.t.Helper()
+
+	key, err := BadgeKey(&badge)
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	var verification BadgeVerification
+	if err := json.Unmarshal(ta.query("/verify_badge", string(key)).Value, &verification); err != nil {
+		ta.t.Fatal(err)
+	}
+
+	return &verification
+}
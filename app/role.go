@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	"github.com/saguywalker/sitcomchain/code"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+const (
+	// RolePrefix define the prefix of role keys
+	RolePrefix string = "role:"
+
+	// RoleAdvisor can propose badges
+	RoleAdvisor string = "advisor"
+
+	// RoleHeadOfDepartment can approve badge proposals
+	RoleHeadOfDepartment string = "head_of_department"
+
+	// RoleOrganizer can create activities
+	RoleOrganizer string = "organizer"
+
+	// RolePrerequisiteOverride can co-sign a badge whose prerequisites are missing
+	RolePrerequisiteOverride string = "prerequisite_override"
+
+	// RoleHistoricalImport can co-sign a badge of a semester which is closed
+	RoleHistoricalImport string = "historical_import"
+)
+
+var roleList = map[string]bool{
+	RoleAdvisor:              true,
+	RoleHeadOfDepartment:     true,
+	RoleOrganizer:            true,
+	RolePrerequisiteOverride: true,
+	RoleHistoricalImport:     true,
+}
+
+func roleKey(role string, publicKey []byte) []byte {
+	return []byte(fmt.Sprintf("%s%s:%s", RolePrefix, role, base64.StdEncoding.EncodeToString(publicKey)))
+}
+
+func (a *SitcomApplication) hasRole(role string, publicKey []byte) bool {
+	return a.state.db.Has(roleKey(role, publicKey))
+}
+
+func (a *SitcomApplication) assignRole(payload []byte) (res types.ResponseDeliverTx, err error) {
+	var param AssignRoleParam
+	if err := json.Unmarshal(payload, &param); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if !roleList[param.Role] {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = fmt.Sprintf("unknown role: %s", param.Role)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if len(param.PublicKey) == 0 {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "public key cannot be empty"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	key := roleKey(param.Role, param.PublicKey)
+	if param.Revoke {
+		a.state.db.Delete(key)
+	} else {
+		a.state.db.Set(key, []byte{})
+	}
+
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+// signedByRole report whether a key holding the role also signed the transaction
+func (a *SitcomApplication) signedByRole(role string, txObj *protoTm.Tx) bool {
+	signBytes, err := SignBytes(txObj.Payload)
+	if err != nil {
+		return false
+	}
+
+	for _, sig := range txObj.Signatures {
+		if a.hasRole(role, sig.PublicKey) && !a.state.db.Has(revokedKey(sig.PublicKey)) && verifySignature(sig.KeyType, sig.PublicKey, signBytes, sig.Signature) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// SemesterPrefix define the prefix of academic calendar keys
+	SemesterPrefix string = "semester:"
+
+	// SemesterBadgePrefix define the prefix of semester to badge index
+	SemesterBadgePrefix string = "semesterbadge:"
+
+	// SemesterGracePeriod is seconds after a semester ends during which badges can still be given
+	SemesterGracePeriod int64 = 30 * 24 * 60 * 60
+)
+
+func semesterKey(id uint32) []byte {
+	return []byte(SemesterPrefix + strconv.FormatUint(uint64(id), 10))
+}
+
+func semesterBadgePrefix(semester uint32) []byte {
+	return []byte(SemesterBadgePrefix + strconv.FormatUint(uint64(semester), 10) + ":")
+}
+
+func semesterBadgeKey(badge *GiveBadge) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s", semesterBadgePrefix(badge.Semester), badge.CompetenceID, badge.StudentID))
+}
+
+func (a *SitcomApplication) getSemester(id uint32) (*Semester, error) {
+	value := a.state.db.Get(semesterKey(id))
+	if value == nil {
+		return nil, fmt.Errorf("semester %d not found", id)
+	}
+
+	var semester Semester
+	if err := json.Unmarshal(value, &semester); err != nil {
+		return nil, err
+	}
+
+	return &semester, nil
+}
+
+// checkSemesterOpen return an error code unless the semester is open
+// or closed within the grace period at the current block time,
+// a historical record is accepted for a semester which has ended
+func (a *SitcomApplication) checkSemesterOpen(id uint32, historical bool) (uint32, string) {
+	semester, err := a.getSemester(id)
+	if err != nil {
+		return code.CodeTypeNotFound, err.Error()
+	}
+
+	if historical && a.state.BlockTime > semester.End {
+		return code.CodeTypeOK, ""
+	}
+
+	if a.state.BlockTime < semester.Start {
+		return code.CodeTypeInvalidParams, fmt.Sprintf("semester %d has not started", id)
+	}
+
+	if a.state.BlockTime > semester.End+SemesterGracePeriod {
+		return code.CodeTypeInvalidParams, fmt.Sprintf("semester %d is closed", id)
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+func (a *SitcomApplication) setSemester(payload []byte, create bool) (res types.ResponseDeliverTx, err error) {
+	var semester Semester
+	if err := json.Unmarshal(payload, &semester); err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = "error when unmarshal params"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	if semester.ID == 0 || semester.Name == "" || semester.End < semester.Start {
+		res.Code = code.CodeTypeInvalidParams
+		res.Log = "semester needs an id, a name and cannot end before it starts"
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	exists := a.state.db.Has(semesterKey(semester.ID))
+	if create && exists {
+		res.Code = code.CodeTypeDuplicateKey
+		res.Log = fmt.Sprintf("semester %d already exists", semester.ID)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	if !create && !exists {
+		res.Code = code.CodeTypeNotFound
+		res.Log = fmt.Sprintf("semester %d not found", semester.ID)
+		a.logger.Infoln(res.Log)
+		return res, errors.New(res.Log)
+	}
+
+	value, err := json.Marshal(semester)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal semester"
+		a.logger.Infoln(res.Log)
+		return res, err
+	}
+
+	a.state.db.Set(semesterKey(semester.ID), value)
+	a.state.Size++
+	res.Code = code.CodeTypeOK
+	res.Log = "success"
+	a.logger.Infoln(res.Log)
+	return res, nil
+}
+
+func (a *SitcomApplication) createSemester(payload []byte) (types.ResponseDeliverTx, error) {
+	return a.setSemester(payload, true)
+}
+
+func (a *SitcomApplication) updateSemester(payload []byte) (types.ResponseDeliverTx, error) {
+	return a.setSemester(payload, false)
+}
+
+func (a *SitcomApplication) querySemester(data []byte) (res types.ResponseQuery) {
+	id, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid semester id"
+		return
+	}
+
+	semester, err := a.getSemester(uint32(id))
+	if err != nil {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = semesterKey(semester.ID)
+	res.Value, err = json.Marshal(semester)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal semester"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+func (a *SitcomApplication) querySemesters(data []byte) (res types.ResponseQuery) {
+	semesters := make([]*Semester, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(SemesterPrefix))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var semester Semester
+		if err := json.Unmarshal(itr.Value(), &semester); err != nil {
+			continue
+		}
+		semesters = append(semesters, &semester)
+	}
+
+	value, err := json.Marshal(semesters)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal semesters"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = value
+	return
+}
+
+// querySemesterBadges list keys of a student's badges given in a semester,
+// separated by "|", data is a StudentProof with a "semester"
+func (a *SitcomApplication) querySemesterBadges(data []byte) (res types.ResponseQuery) {
+	studentID, resCode, resLog := provenStudent(data)
+	if resCode != code.CodeTypeOK {
+		res.Code = resCode
+		res.Log = resLog
+		return
+	}
+
+	var param struct {
+		Semester uint32 `json:"semester"`
+	}
+	if err := json.Unmarshal(data, &param); err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid semester id"
+		return
+	}
+
+	badgeKeys := make([][]byte, 0)
+	suffix := []byte(":" + studentID)
+	itr := dbm.IteratePrefix(a.state.db, semesterBadgePrefix(param.Semester))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		if bytes.HasSuffix(itr.Key(), suffix) {
+			badgeKeys = append(badgeKeys, itr.Value())
+		}
+	}
+
+	if len(badgeKeys) == 0 {
+		res.Log = "does not exists"
+		return
+	}
+
+	res.Log = "exists"
+	res.Value = bytes.Join(badgeKeys, []byte("|"))
+	return
+}
+
+func (a *SitcomApplication) querySemesterStats(data []byte) (res types.ResponseQuery) {
+	id, err := strconv.ParseUint(string(data), 10, 32)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid semester id"
+		return
+	}
+
+	res.Value, err = json.Marshal(a.semesterStats(uint32(id)))
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal stats"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+// semesterStats return badge counts of a semester from its running counters
+func (a *SitcomApplication) semesterStats(id uint32) *SemesterStats {
+	stats := SemesterStats{
+		Semester:     id,
+		Students:     a.getCounter(statsStudentsKey(id)),
+		ByCompetence: make(map[uint32]uint64),
+	}
+
+	prefix := statsBadgePrefix(id)
+	itr := dbm.IteratePrefix(a.state.db, prefix)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		competenceID, err := strconv.ParseUint(string(bytes.TrimPrefix(itr.Key(), prefix)), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		count := a.getCounter(itr.Key())
+		stats.Badges += count
+		stats.ByCompetence[uint32(competenceID)] = count
+	}
+
+	return &stats
+}
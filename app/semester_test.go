@@ -0,0 +1,109 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+// semesterBadges return the keys of a student's badges given in a semester
+func (ta *testApp) semesterBadges(id string, semester uint32) [][]byte {
+	ta.t.Helper()
+
+	data := fmt.Sprintf(`{"student_id":%q,"secret":%q,"semester":%d}`, id, base64.StdEncoding.EncodeToString(ta.secret(id)), semester)
+	res := ta.query("/semester_badges", data)
+	if res.Code != code.CodeTypeOK {
+		ta.t.Fatalf("/semester_badges: %s", res.Log)
+	}
+	if len(res.Value) == 0 {
+		return nil
+	}
+
+	return bytes.Split(res.Value, []byte("|"))
+}
+
+func TestAcademicCalendar(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	ta.block(1)
+	admin := ta.admin()
+	ta.must(code.CodeTypeOK, "CreateCompetence", Competence{ID: 1, Name: "Teamwork"}, admin)
+
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 1, Name: "1/2019", Start: 100, End: 200}, admin)
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 2, Name: "2/2019", Start: 300, End: 400}, admin)
+	ta.must(code.CodeTypeDuplicateKey, "CreateSemester", Semester{ID: 1, Name: "1/2019", Start: 100, End: 200}, admin)
+	ta.must(code.CodeTypeInvalidParams, "CreateSemester", Semester{ID: 3, Name: "3/2019", Start: 200, End: 100}, admin)
+	ta.must(code.CodeTypeNotFound, "UpdateSemester", Semester{ID: 3, Name: "3/2019", Start: 500, End: 600}, admin)
+	ta.must(code.CodeTypeOK, "UpdateSemester", Semester{ID: 2, Name: "2/2019", Start: 300, End: 450}, admin)
+
+	var semester Semester
+	ta.queryJSON("/semester", "2", &semester)
+	if semester.End != 450 {
+		t.Fatalf("expected the updated semester, got %+v", semester)
+	}
+	var semesters []Semester
+	ta.queryJSON("/semesters", "", &semesters)
+	if len(semesters) != 2 {
+		t.Fatalf("expected 2 semesters, got %+v", semesters)
+	}
+
+	// block 30 is at time 150, within semester 1 and before semester 2
+	ta.block(30)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}, admin)
+	ta.must(code.CodeTypeInvalidParams, "GiveBadge", GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 2}, admin)
+	ta.must(code.CodeTypeNotFound, "GiveBadge", GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 3}, admin)
+
+	// after the grace period only a historical import can give a badge of semester 1
+	ta.block((200+SemesterGracePeriod)/5 + 1)
+	late := GiveBadge{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeInvalidParams, "GiveBadge", late, admin)
+
+	importerKey, importer := ta.newKey()
+	ta.must(code.CodeTypeOK, "AssignRole", AssignRoleParam{PublicKey: importerKey, Role: RoleHistoricalImport}, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", late, admin, importer)
+}
+
+func TestSemesterBadges(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 2, Name: "2/2019", Start: 0, End: 1 << 40}, admin)
+
+	first := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	second := GiveBadge{StudentID: ta.student("1"), CompetenceID: 2, Semester: 1}
+	for _, badge := range []GiveBadge{
+		first,
+		second,
+		{StudentID: ta.student("1"), CompetenceID: 1, Semester: 2},
+		{StudentID: ta.student("2"), CompetenceID: 1, Semester: 1},
+	} {
+		ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+	}
+
+	keys := ta.semesterBadges("1", 1)
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 badges of semester 1, got %q", keys)
+	}
+	for _, badge := range []GiveBadge{first, second} {
+		key, err := BadgeKey(&badge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(keys[0], key) && !bytes.Equal(keys[1], key) {
+			t.Fatalf("badge %s is missing from %q", key, keys)
+		}
+	}
+
+	if keys := ta.semesterBadges("1", 2); len(keys) != 1 {
+		t.Fatalf("expected 1 badge of semester 2, got %q", keys)
+	}
+	if keys := ta.semesterBadges("3", 1); len(keys) != 0 {
+		t.Fatalf("expected no badge of student 3, got %q", keys)
+	}
+}
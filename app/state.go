@@ -12,8 +12,13 @@ var (
 
 // StateMetaData struct
 type StateMetaData struct {
-	Height  int64  `json:"height"`
-	AppHash []byte `json:"app_hash"`
+	Height    int64  `json:"height"`
+	AppHash   []byte `json:"app_hash"`
+	ChainID   string `json:"chain_id"`
+	BlockTime int64  `json:"block_time"`
+
+	// ValidatorKeyTypes are the key types the consensus params allow for validators
+	ValidatorKeyTypes []string `json:"validator_key_types,omitempty"`
 }
 
 // State contains current state data
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// StatsIssuerPrefix define the prefix of badge counters by semester and issuer
+	StatsIssuerPrefix string = "statsissuer:"
+
+	// StatsBadgePrefix define the prefix of badge counters by semester and competence
+	StatsBadgePrefix string = "statsbadge:"
+
+	// StatsStudentsPrefix define the prefix of counters of students with a badge by semester
+	StatsStudentsPrefix string = "statsstudents:"
+
+	// StatsStudentPrefix define the prefix of markers of a student counted in a semester
+	StatsStudentPrefix string = "statsstudent:"
+
+	// StatsActivityPrefix define the prefix of attendance and approval counters of activities
+	StatsActivityPrefix string = "statsactivity:"
+
+	// DefaultTopIssuers is the number of issuers returned by /top_issuers without a limit
+	DefaultTopIssuers int = 10
+)
+
+func statsIssuerKey(semester uint32, issuer string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s", StatsIssuerPrefix, semester, issuer))
+}
+
+func statsBadgePrefix(semester uint32) []byte {
+	return []byte(fmt.Sprintf("%s%d:", StatsBadgePrefix, semester))
+}
+
+func statsBadgeKey(semester uint32, competenceID uint32) []byte {
+	return append(statsBadgePrefix(semester), strconv.FormatUint(uint64(competenceID), 10)...)
+}
+
+func statsStudentsKey(semester uint32) []byte {
+	return []byte(fmt.Sprintf("%s%d", StatsStudentsPrefix, semester))
+}
+
+func statsStudentKey(semester uint32, studentID string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s", StatsStudentPrefix, semester, studentID))
+}
+
+func statsActivityKey(activityID uint32, counter string) []byte {
+	return []byte(fmt.Sprintf("%s%d:%s", StatsActivityPrefix, activityID, counter))
+}
+
+func (a *SitcomApplication) getCounter(key []byte) uint64 {
+	if value := a.state.db.Get(key); len(value) == 8 {
+		return binary.BigEndian.Uint64(value)
+	}
+
+	return 0
+}
+
+// incrCounter increase a counter and return its new value
+func (a *SitcomApplication) incrCounter(key []byte) uint64 {
+	count := a.getCounter(key) + 1
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, count)
+	a.state.db.Set(key, value)
+	return count
+}
+
+// countBadge count a given badge by semester and competence, its student once
+// per semester, and by each identity which signed it, a signer without
+// identity is counted by its public key
+func (a *SitcomApplication) countBadge(badge *GiveBadge, signers [][]byte) error {
+	a.incrCounter(statsBadgeKey(badge.Semester, badge.CompetenceID))
+	if studentKey := statsStudentKey(badge.Semester, badge.StudentID); !a.state.db.Has(studentKey) {
+		a.state.db.Set(studentKey, []byte{})
+		a.incrCounter(statsStudentsKey(badge.Semester))
+	}
+
+	issuers := make(map[string]bool)
+	for _, signer := range signers {
+		issuer := base64.StdEncoding.EncodeToString(signer)
+		identity, err := a.identityOf(signer)
+		if err != nil {
+			return err
+		}
+		if identity != nil {
+			issuer = identity.ID
+		}
+
+		if !issuers[issuer] {
+			issuers[issuer] = true
+			a.incrCounter(statsIssuerKey(badge.Semester, issuer))
+		}
+	}
+
+	return nil
+}
+
+// parseSemester parse an optional semester id, 0 when data is empty
+func parseSemester(data []byte) (uint32, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(string(data), 10, 32)
+	return uint32(id), err
+}
+
+// queryBadgeStats return badge counts of a semester as /semester_stats
+// does, or of every semester when data is empty
+func (a *SitcomApplication) queryBadgeStats(data []byte) (res types.ResponseQuery) {
+	semester, err := parseSemester(data)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid semester id"
+		return
+	}
+
+	semesters := []uint32{semester}
+	if semester == 0 {
+		semesters = semesters[:0]
+		itr := dbm.IteratePrefix(a.state.db, []byte(SemesterPrefix))
+		for ; itr.Valid(); itr.Next() {
+			var item Semester
+			if err := json.Unmarshal(itr.Value(), &item); err == nil {
+				semesters = append(semesters, item.ID)
+			}
+		}
+		itr.Close()
+	}
+
+	result := make([]*SemesterStats, 0, len(semesters))
+	for _, id := range semesters {
+		result = append(result, a.semesterStats(id))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Semester < result[j].Semester })
+
+	res.Value, err = json.Marshal(result)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal stats"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+// queryParticipation return enrollment, attendance and approval counts of
+// activities of a semester, or of every activity when data is empty
+func (a *SitcomApplication) queryParticipation(data []byte) (res types.ResponseQuery) {
+	semester, err := parseSemester(data)
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid semester id"
+		return
+	}
+
+	result := make([]ActivityParticipation, 0)
+	itr := dbm.IteratePrefix(a.state.db, []byte(ActivityPrefix))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var activity Activity
+		if err := json.Unmarshal(itr.Value(), &activity); err != nil {
+			continue
+		}
+
+		if semester != 0 && activity.Semester != semester {
+			continue
+		}
+
+		participation := ActivityParticipation{
+			ActivityID: activity.ID,
+			Title:      activity.Title,
+			Semester:   activity.Semester,
+			Capacity:   activity.Capacity,
+			Enrolled:   uint64(activity.Enrolled),
+			Attended:   a.getCounter(statsActivityKey(activity.ID, "attended")),
+			Approved:   a.getCounter(statsActivityKey(activity.ID, "approved")),
+		}
+		if participation.Enrolled > 0 {
+			participation.AttendanceRate = float64(participation.Attended) / float64(participation.Enrolled)
+			participation.ApprovalRate = float64(participation.Approved) / float64(participation.Enrolled)
+		}
+
+		result = append(result, participation)
+	}
+
+	res.Value, err = json.Marshal(result)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal participation"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
+
+// queryTopIssuers return issuers who gave the most badges, data is a semester
+// id, empty for every semester, optionally followed by ":" and a limit
+func (a *SitcomApplication) queryTopIssuers(data []byte) (res types.ResponseQuery) {
+	parts := bytes.SplitN(data, []byte(":"), 2)
+	semester, err := parseSemester(parts[0])
+	if err != nil {
+		res.Code = code.CodeTypeDecodingError
+		res.Log = "invalid semester id"
+		return
+	}
+
+	limit := DefaultTopIssuers
+	if len(parts) == 2 {
+		limit, err = strconv.Atoi(string(parts[1]))
+		if err != nil || limit <= 0 {
+			res.Code = code.CodeTypeInvalidParams
+			res.Log = "invalid limit"
+			return
+		}
+	}
+
+	prefix := []byte(StatsIssuerPrefix)
+	if semester != 0 {
+		prefix = []byte(fmt.Sprintf("%s%d:", StatsIssuerPrefix, semester))
+	}
+
+	counts := make(map[string]uint64)
+	itr := dbm.IteratePrefix(a.state.db, prefix)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		parts := bytes.SplitN(bytes.TrimPrefix(itr.Key(), []byte(StatsIssuerPrefix)), []byte(":"), 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		counts[string(parts[1])] += binary.BigEndian.Uint64(itr.Value())
+	}
+
+	result := make([]IssuerCount, 0, len(counts))
+	for issuer, badges := range counts {
+		result = append(result, IssuerCount{Issuer: issuer, Badges: badges})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Badges != result[j].Badges {
+			return result[i].Badges > result[j].Badges
+		}
+		return result[i].Issuer < result[j].Issuer
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	res.Value, err = json.Marshal(result)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal issuers"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestBadgeStats(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+	ta.must(code.CodeTypeOK, "CreateSemester", Semester{ID: 2, Name: "2/2019", Start: 0, End: 1 << 40}, admin)
+
+	advisorKey, advisor := ta.newKey()
+	ta.must(code.CodeTypeOK, "CreateMultisig", MultisigAccount{Name: "advisors", Threshold: 1, PublicKeys: [][]byte{advisorKey}}, admin)
+	ta.must(code.CodeTypeOK, "SetSignPolicy", SignPolicy{Method: "GiveBadge", CompetenceID: 2, Account: "advisors"}, admin)
+
+	first, second := ta.student("1"), ta.student("2")
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: first, CompetenceID: 1, Semester: 1}, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: second, CompetenceID: 1, Semester: 1}, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: first, CompetenceID: 2, Semester: 1}, advisor)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: first, CompetenceID: 1, Semester: 2}, admin)
+
+	// a failed batch is not counted
+	ta.must(code.CodeTypeDuplicateKey, "GiveBadgeBatch", GiveBadgeBatch{Entries: []GiveBadge{
+		{StudentID: ta.student("3"), CompetenceID: 1, Semester: 1},
+		{StudentID: first, CompetenceID: 1, Semester: 1},
+	}}, admin)
+
+	var stats []SemesterStats
+	ta.queryJSON("/badge_stats", "", &stats)
+	expected := []SemesterStats{
+		{Semester: 1, Badges: 3, Students: 2, ByCompetence: map[uint32]uint64{1: 2, 2: 1}},
+		{Semester: 2, Badges: 1, Students: 1, ByCompetence: map[uint32]uint64{1: 1}},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+
+	var semester SemesterStats
+	ta.queryJSON("/semester_stats", "1", &semester)
+	if !reflect.DeepEqual(semester, expected[0]) {
+		t.Fatalf("expected %+v, got %+v", expected[0], semester)
+	}
+
+	var issuers []IssuerCount
+	ta.queryJSON("/top_issuers", "", &issuers)
+	adminIssuer := base64.StdEncoding.EncodeToString(admin.Public().(ed25519.PublicKey))
+	advisorIssuer := base64.StdEncoding.EncodeToString(advisorKey)
+	if len(issuers) != 2 || issuers[0] != (IssuerCount{Issuer: adminIssuer, Badges: 3}) || issuers[1] != (IssuerCount{Issuer: advisorIssuer, Badges: 1}) {
+		t.Fatalf("unexpected top issuers %+v", issuers)
+	}
+
+	ta.queryJSON("/top_issuers", "2:1", &issuers)
+	if len(issuers) != 1 || issuers[0].Badges != 1 {
+		t.Fatalf("expected one issuer of semester 2, got %+v", issuers)
+	}
+
+	if res := ta.query("/top_issuers", "1:0"); res.Code != code.CodeTypeInvalidParams {
+		t.Fatalf("expected code %d for a zero limit, got %d", code.CodeTypeInvalidParams, res.Code)
+	}
+	if res := ta.query("/badge_stats", "first"); res.Code != code.CodeTypeDecodingError {
+		t.Fatalf("expected code %d for an invalid semester, got %d", code.CodeTypeDecodingError, res.Code)
+	}
+}
+
+func TestParticipation(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin)
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Semester: 1, Capacity: 10}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		ta.must(code.CodeTypeOK, "EnrollActivity", EnrollmentParam{ActivityID: 1, StudentID: ta.student(id)}, organizer)
+	}
+	for _, id := range []string{"1", "2"} {
+		ta.must(code.CodeTypeOK, "RecordAttendance", EnrollmentParam{ActivityID: 1, StudentID: ta.student(id)}, organizer)
+	}
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: ta.student("1"), ActivityID: 1}, organizer)
+
+	var participation []ActivityParticipation
+	ta.queryJSON("/participation", "1", &participation)
+	expected := ActivityParticipation{
+		ActivityID:     1,
+		Title:          "Hackathon",
+		Semester:       1,
+		Capacity:       10,
+		Enrolled:       4,
+		Attended:       2,
+		Approved:       1,
+		AttendanceRate: 0.5,
+		ApprovalRate:   0.25,
+	}
+	if len(participation) != 1 || participation[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, participation)
+	}
+
+	ta.queryJSON("/participation", "2", &participation)
+	if len(participation) != 0 {
+		t.Fatalf("expected no activity in semester 2, got %+v", participation)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/saguywalker/sitcomchain/code"
+	"github.com/saguywalker/sitcomchain/student"
+)
+
+// StudentProof is the student ID and disclosure secret behind a commitment.
+// Queries which list a student's badges take it instead of the commitment,
+// so a verifier who learned the commitment from a disclosed badge cannot
+// list the student's other badges.
+type StudentProof struct {
+	StudentID string `json:"student_id"`
+	Secret    []byte `json:"secret"`
+}
+
+// studentIndexPrefixes are prefixes of keys which list or count a student's
+// badges and approvals, Query does not serve them raw
+var studentIndexPrefixes = []string{
+	PortfolioPrefix,
+	StudentBadgePrefix,
+	SemesterBadgePrefix,
+	StudentApprovalPrefix,
+	AwardCountPrefix,
+	StatsStudentPrefix,
+}
+
+// checkStudentID return an error code unless the student ID is a
+// pseudonymous commitment, plaintext student IDs never reach the chain
+func checkStudentID(studentID string) (uint32, string) {
+	if !student.IsCommitment(studentID) {
+		return code.CodeTypeInvalidParams, "student_id must be a commitment of the student ID and secret, see student.Commit"
+	}
+
+	return code.CodeTypeOK, ""
+}
+
+// provenStudent return the commitment of the student whose proof is the query data
+func provenStudent(data []byte) (string, uint32, string) {
+	var proof StudentProof
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return "", code.CodeTypeUnmarshalError, "query data must be a student proof, {\"student_id\", \"secret\"}"
+	}
+
+	commitment, err := student.Commit(proof.StudentID, proof.Secret)
+	if err != nil {
+		return "", code.CodeTypeInvalidParams, err.Error()
+	}
+
+	return commitment, code.CodeTypeOK, ""
+}
+
+// isStudentKey report whether a key holds a student's badge or approval,
+// or indexes them
+func isStudentKey(key []byte) bool {
+	if bytes.HasPrefix(key, []byte("{")) {
+		return true
+	}
+
+	return isStudentIndex(key)
+}
+
+// isStudentIndex report whether a key lists or counts a student's badges or approvals
+func isStudentIndex(key []byte) bool {
+	for _, prefix := range studentIndexPrefixes {
+		if bytes.HasPrefix(key, []byte(prefix)) {
+			return true
+		}
+	}
+
+	return false
+}
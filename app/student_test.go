@@ -0,0 +1,71 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestPlaintextStudentIDRejected(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	ta.must(code.CodeTypeInvalidParams, "GiveBadge", GiveBadge{StudentID: "6030123", CompetenceID: 1, Semester: 1}, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: ta.student("6030123"), CompetenceID: 1, Semester: 1}, admin)
+}
+
+func TestStudentBadgesNeedProof(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"})
+
+	studentID := ta.student("1")
+	badge := GiveBadge{StudentID: studentID, CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", badge, admin)
+	badgeKey, err := BadgeKey(&badge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongSecret, err := json.Marshal(StudentProof{StudentID: "1", Secret: []byte("a guess")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/progress", "/openbadges", "/transcript", "/semester_badges"} {
+		if res := ta.query(path, studentID); res.Code == code.CodeTypeOK {
+			t.Fatalf("%s served badges by the commitment: %s", path, res.Value)
+		}
+
+		// the wrong secret gives another commitment without badges
+		if res := ta.query(path, string(wrongSecret)); bytes.Contains(res.Value, badgeKey) {
+			t.Fatalf("%s served badges with the wrong secret: %s", path, res.Value)
+		}
+	}
+
+	res := ta.query("/semester_badges", `{"student_id":"1","secret":"`+base64.StdEncoding.EncodeToString(ta.secret("1"))+`","semester":1}`)
+	if res.Code != code.CodeTypeOK || !bytes.Equal(res.Value, badgeKey) {
+		t.Fatalf("expected the badge of semester 1, got %d %s: %s", res.Code, res.Log, res.Value)
+	}
+	if res := ta.query("/openbadges", ta.studentProof("1")); res.Code != code.CodeTypeOK || res.Log != "exists" {
+		t.Fatalf("expected the student's open badges, got %d: %s", res.Code, res.Log)
+	}
+
+	// the raw indexes are not served either
+	for _, key := range [][]byte{portfolioKey(studentID), studentBadgeKey(&badge), semesterBadgeKey(&badge)} {
+		if res := ta.query("", string(key)); res.Code != code.CodeTypeUnauthorized {
+			t.Fatalf("raw %s: expected code %d, got %d", key, code.CodeTypeUnauthorized, res.Code)
+		}
+	}
+	if res := ta.query("", string(badgeKey)); res.Log != "exists" {
+		t.Fatalf("a badge key can still be verified, got %s", res.Log)
+	}
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+const (
+	// StudentApprovalPrefix define the prefix of student to approved activity index
+	StudentApprovalPrefix string = "studentapproval:"
+)
+
+func studentApprovalPrefix(studentID string) []byte {
+	return []byte(StudentApprovalPrefix + studentID + ":")
+}
+
+// studentApprovalKey order a student's approvals by height, then by activity
+func studentApprovalKey(studentID string, height int64, activityID uint32) []byte {
+	return append(studentApprovalPrefix(studentID), fmt.Sprintf("%020d:%010d", height, activityID)...)
+}
+
+// indexApproval record the first approval of a student's activity
+func (a *SitcomApplication) indexApproval(approval *ApproveActivity, activityKey []byte, signers [][]byte) error {
+	value, err := json.Marshal(ActivityApproval{
+		ActivityID:  approval.ActivityID,
+		ActivityKey: string(activityKey),
+		Height:      a.state.Height,
+		Time:        a.state.BlockTime,
+		Approvers:   signers,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.state.db.Set(studentApprovalKey(approval.StudentID, a.state.Height, approval.ActivityID), value)
+	return nil
+}
+
+// signerStatus check a key which signed at height against identities and
+// revocations, its signature is taken as verified
+func (a *SitcomApplication) signerStatus(signer []byte, height int64) (SignerStatus, *Identity, error) {
+	status := SignerStatus{PublicKey: signer, Verified: true}
+
+	identity, err := a.identityOf(signer)
+	if err != nil {
+		return status, nil, err
+	}
+	if identity != nil {
+		status.Identity = identity.ID
+		status.Active = identity.activeAt(signer, height)
+	}
+
+	revocation, err := a.getRevocation(signer)
+	if err != nil {
+		return status, nil, err
+	}
+	if revocation != nil {
+		status.Revoked = true
+		status.Compromised = height >= revocation.CompromisedAt
+	}
+
+	return status, identity, nil
+}
+
+// getTranscript collect a student's badges in portfolio order and approved
+// activities in approval order, with the identities which issued them
+func (a *SitcomApplication) getTranscript(studentID string) (*Transcript, error) {
+	transcript := Transcript{
+		StudentID:  studentID,
+		Height:     a.state.Height,
+		Badges:     make([]TranscriptBadge, 0),
+		Activities: make([]TranscriptActivity, 0),
+		Revoked:    make([]string, 0),
+		Issuers:    make(map[string]*Identity),
+	}
+
+	addIssuer := func(id string) error {
+		if _, exists := transcript.Issuers[id]; id == "" || exists {
+			return nil
+		}
+		identity, err := a.getIdentity(id)
+		if err != nil {
+			return err
+		}
+		transcript.Issuers[id] = identity
+		return nil
+	}
+
+	badgeKeys, err := a.getPortfolio(studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, badgeKey := range badgeKeys {
+		item := TranscriptBadge{BadgeKey: badgeKey, Signers: make([]SignerStatus, 0)}
+		if err := json.Unmarshal(a.state.db.Get([]byte(badgeKey)), &item.Badge); err != nil {
+			return nil, err
+		}
+
+		// a badge given before issuances were recorded cannot be verified,
+		// it is listed as unverified rather than failing the transcript
+		if !a.state.db.Has(issuanceKey([]byte(badgeKey))) {
+			transcript.Badges = append(transcript.Badges, item)
+			continue
+		}
+
+		verification, err := a.verifyIssuance([]byte(badgeKey))
+		if err != nil {
+			return nil, err
+		}
+		item.Height = verification.Height
+		item.Time = verification.Time
+		item.TxHash = verification.TxHash
+		item.Signers = verification.Signers
+		item.Valid = verification.Valid
+
+		for _, signer := range item.Signers {
+			if err := addIssuer(signer.Identity); err != nil {
+				return nil, err
+			}
+		}
+		if !item.Valid {
+			transcript.Revoked = append(transcript.Revoked, badgeKey)
+		}
+		transcript.Badges = append(transcript.Badges, item)
+	}
+
+	itr := dbm.IteratePrefix(a.state.db, studentApprovalPrefix(studentID))
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		var approval ActivityApproval
+		if err := json.Unmarshal(itr.Value(), &approval); err != nil {
+			return nil, err
+		}
+
+		item := TranscriptActivity{
+			ActivityID:  approval.ActivityID,
+			ActivityKey: approval.ActivityKey,
+			Height:      approval.Height,
+			Time:        approval.Time,
+			Approvers:   make([]SignerStatus, 0, len(approval.Approvers)),
+			Valid:       true,
+		}
+
+		if activity, err := a.getActivity(approval.ActivityID); err == nil {
+			item.Title = activity.Title
+			item.Semester = activity.Semester
+		}
+
+		for _, approver := range approval.Approvers {
+			status, identity, err := a.signerStatus(approver, approval.Height)
+			if err != nil {
+				return nil, err
+			}
+			if status.Compromised || (identity != nil && !status.Active) {
+				item.Valid = false
+			}
+			if err := addIssuer(status.Identity); err != nil {
+				return nil, err
+			}
+			item.Approvers = append(item.Approvers, status)
+		}
+
+		if !item.Valid {
+			transcript.Revoked = append(transcript.Revoked, item.ActivityKey)
+		}
+		transcript.Activities = append(transcript.Activities, item)
+	}
+
+	return &transcript, nil
+}
+
+// queryTranscript return a student's transcript, data is a StudentProof
+func (a *SitcomApplication) queryTranscript(data []byte) (res types.ResponseQuery) {
+	studentID, resCode, resLog := provenStudent(data)
+	if resCode != code.CodeTypeOK {
+		res.Code = resCode
+		res.Log = resLog
+		return
+	}
+
+	transcript, err := a.getTranscript(studentID)
+	if err != nil {
+		res.Code = code.CodeTypeUnmarshalError
+		res.Log = err.Error()
+		return
+	}
+
+	if len(transcript.Badges) == 0 && len(transcript.Activities) == 0 {
+		res.Log = "does not exist"
+		return
+	}
+
+	res.Key = []byte(studentID)
+	res.Value, err = json.Marshal(transcript)
+	if err != nil {
+		res.Code = code.CodeTypeEncodingError
+		res.Log = "error when marshal transcript"
+		return
+	}
+
+	res.Log = "exists"
+	return
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/saguywalker/sitcomchain/code"
+)
+
+func TestTranscript(t *testing.T) {
+	ta, admin, organizer := newActivityApp(t)
+	defer ta.close()
+
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+	ta.must(code.CodeTypeOK, "CreateActivity", Activity{ID: 1, Title: "Hackathon", Semester: 1, Capacity: 10}, organizer)
+	ta.must(code.CodeTypeOK, "SetActivityState", ActivityStateParam{ActivityID: 1, State: ActivityOpen}, organizer)
+
+	first := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	second := GiveBadge{StudentID: ta.student("1"), CompetenceID: 2, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", first, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", second, admin)
+	ta.must(code.CodeTypeOK, "ApproveActivity", ApproveActivity{StudentID: ta.student("1"), ActivityID: 1}, organizer)
+
+	var transcript Transcript
+	ta.queryJSON("/transcript", ta.studentProof("1"), &transcript)
+	if len(transcript.Badges) != 2 || len(transcript.Activities) != 1 || len(transcript.Revoked) != 0 {
+		t.Fatalf("expected 2 badges and 1 activity, got %+v", transcript)
+	}
+	for i, badge := range []GiveBadge{first, second} {
+		item := transcript.Badges[i]
+		if item.Badge.CompetenceID != badge.CompetenceID || !item.Valid || len(item.Signers) != 1 || item.TxHash == "" {
+			t.Fatalf("badge %d: unexpected %+v", i, item)
+		}
+	}
+	if activity := transcript.Activities[0]; activity.Title != "Hackathon" || !activity.Valid || len(activity.Approvers) != 1 {
+		t.Fatalf("unexpected activity %+v", activity)
+	}
+
+	if res := ta.query("/transcript", ta.studentProof("2")); res.Code != code.CodeTypeOK || res.Log != "does not exist" {
+		t.Fatalf("expected no transcript of student 2, got %d: %s", res.Code, res.Log)
+	}
+}
+
+func TestTranscriptBadgeWithoutIssuance(t *testing.T) {
+	ta := newTestApp(t)
+	defer ta.close()
+
+	admin := ta.admin()
+	ta.catalog(admin, Competence{ID: 1, Name: "Teamwork"}, Competence{ID: 2, Name: "Leadership"})
+
+	first := GiveBadge{StudentID: ta.student("1"), CompetenceID: 1, Semester: 1}
+	ta.must(code.CodeTypeOK, "GiveBadge", first, admin)
+	ta.must(code.CodeTypeOK, "GiveBadge", GiveBadge{StudentID: ta.student("1"), CompetenceID: 2, Semester: 1}, admin)
+
+	// a badge given before issuances were recorded
+	key, err := BadgeKey(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.app.state.db.Delete(issuanceKey(key))
+
+	var transcript Transcript
+	ta.queryJSON("/transcript", ta.studentProof("1"), &transcript)
+	if len(transcript.Badges) != 2 {
+		t.Fatalf("expected 2 badges, got %+v", transcript.Badges)
+	}
+
+	unverified, verified := transcript.Badges[0], transcript.Badges[1]
+	if unverified.BadgeKey != string(key) || unverified.Valid || len(unverified.Signers) != 0 || unverified.TxHash != "" {
+		t.Fatalf("expected the badge without issuance unverified, got %+v", unverified)
+	}
+	if !verified.Valid || len(verified.Signers) != 1 {
+		t.Fatalf("expected the other badge verified, got %+v", verified)
+	}
+	if len(transcript.Revoked) != 0 {
+		t.Fatalf("an unverified badge is not revoked, got %v", transcript.Revoked)
+	}
+}
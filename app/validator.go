@@ -87,8 +87,18 @@ func (app *SitcomApplication) setValidator(param string) types.ResponseDeliverTx
 				Log:  err.Error()}
 		}
 	*/
+	if funcParam.KeyType == "" {
+		funcParam.KeyType = KeyTypeEd25519
+	}
+
+	if err := app.validateValidatorKey(funcParam.KeyType, funcParam.PublicKey); err != nil {
+		return types.ResponseDeliverTx{
+			Code: code.CodeTypeInvalidParams,
+			Log:  err.Error()}
+	}
+
 	var pubKeyObj types.PubKey
-	pubKeyObj.Type = "ed25519"
+	pubKeyObj.Type = funcParam.KeyType
 	// pubKeyObj.Data = pubKey
 	pubKeyObj.Data = funcParam.PublicKey
 	var newValidator types.ValidatorUpdate
@@ -96,3 +106,48 @@ func (app *SitcomApplication) setValidator(param string) types.ResponseDeliverTx
 	newValidator.Power = funcParam.Power
 	return app.updateValidator(newValidator)
 }
+
+// validatorKeyTypes return the key types the consensus params allow for
+// validators, Tendermint defaults to ed25519 only
+func (app *SitcomApplication) validatorKeyTypes() []string {
+	if len(app.state.ValidatorKeyTypes) == 0 {
+		return []string{KeyTypeEd25519}
+	}
+
+	return app.state.ValidatorKeyTypes
+}
+
+// validateValidatorKey check a validator's public key against its type and
+// the key types of the consensus params. Tendermint halts on a validator
+// update of another type, so it never reaches EndBlock.
+func (app *SitcomApplication) validateValidatorKey(keyType string, publicKey []byte) error {
+	if err := validatePublicKey(keyType, publicKey); err != nil {
+		return err
+	}
+
+	for _, allowed := range app.validatorKeyTypes() {
+		if keyType == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("validator key type %s is not allowed, allowed types are %v", keyType, app.validatorKeyTypes())
+}
+
+// checkValidatorParams check params of SetValidator before they enter the mempool
+func (app *SitcomApplication) checkValidatorParams(params []byte) (uint32, string) {
+	var funcParam SetValidatorParam
+	if err := json.Unmarshal(params, &funcParam); err != nil {
+		return code.CodeTypeUnmarshalError, err.Error()
+	}
+
+	if funcParam.KeyType == "" {
+		funcParam.KeyType = KeyTypeEd25519
+	}
+
+	if err := app.validateValidatorKey(funcParam.KeyType, funcParam.PublicKey); err != nil {
+		return code.CodeTypeInvalidParams, err.Error()
+	}
+
+	return code.CodeTypeOK, ""
+}
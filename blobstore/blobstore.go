@@ -0,0 +1,103 @@
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// MaxBlobSize is the maximum size of a blob
+const MaxBlobSize = 32 << 20
+
+// Store is a content-addressed blob store on the local filesystem,
+// a blob is kept under the sha256 of its content in lowercase hex
+type Store struct {
+	dir string
+}
+
+// NewStore open a store in dir
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+// validHash report whether hash is a sha256 in lowercase hex
+func validHash(hash string) bool {
+	decoded, err := hex.DecodeString(hash)
+	return err == nil && len(decoded) == sha256.Size && hex.EncodeToString(decoded) == hash
+}
+
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// Put store a blob read from r and return its hash, storing the same blob twice is a no-op
+func (s *Store) Put(r io.Reader) (string, int64, error) {
+	tmp, err := ioutil.TempFile(s.dir, "upload-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), io.LimitReader(r, MaxBlobSize+1))
+	if err != nil {
+		return "", 0, err
+	}
+
+	if size > MaxBlobSize {
+		return "", 0, fmt.Errorf("blob is larger than %d bytes", MaxBlobSize)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return "", 0, err
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if err := os.MkdirAll(filepath.Dir(s.path(hash)), 0755); err != nil {
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(hash)); err != nil {
+		return "", 0, err
+	}
+
+	return hash, size, nil
+}
+
+// Get return the blob of a hash after checking that its content still matches the hash
+func (s *Store) Get(hash string) ([]byte, error) {
+	if !validHash(hash) {
+		return nil, fmt.Errorf("%q is not a sha256 in lowercase hex", hash)
+	}
+
+	content, err := ioutil.ReadFile(s.path(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := sha256.Sum256(content)
+	if hex.EncodeToString(hashed[:]) != hash {
+		return nil, fmt.Errorf("blob %s is corrupted", hash)
+	}
+
+	return content, nil
+}
+
+// Has report whether the store holds a blob of hash
+func (s *Store) Has(hash string) bool {
+	if !validHash(hash) {
+		return false
+	}
+
+	_, err := os.Stat(s.path(hash))
+	return err == nil
+}
@@ -0,0 +1,140 @@
+package blobstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+const token = "a token of the uploader"
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewStore(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestPutGet(t *testing.T) {
+	store, close := newTestStore(t)
+	defer close()
+
+	content := []byte("certificate of the hackathon")
+	hash, size, err := store.Put(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashed := sha256.Sum256(content)
+	if hash != hex.EncodeToString(hashed[:]) || size != int64(len(content)) {
+		t.Fatalf("expected hash %x of %d bytes, got %s of %d bytes", hashed, len(content), hash, size)
+	}
+
+	// the same blob is kept once under the same hash
+	if again, _, err := store.Put(bytes.NewReader(content)); err != nil || again != hash {
+		t.Fatalf("expected hash %s again, got %s: %v", hash, again, err)
+	}
+
+	got, err := store.Get(hash)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("expected the stored blob, got %q: %v", got, err)
+	}
+	if !store.Has(hash) || store.Has("not a hash") {
+		t.Fatal("unexpected result of Has")
+	}
+
+	if _, err := store.Get("../" + hash); err == nil {
+		t.Fatal("got a blob of an invalid hash")
+	}
+
+	if err := ioutil.WriteFile(store.path(hash), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(hash); err == nil {
+		t.Fatal("got a blob which does not match its hash")
+	}
+}
+
+func TestPutSizeLimit(t *testing.T) {
+	store, close := newTestStore(t)
+	defer close()
+
+	if _, size, err := store.Put(bytes.NewReader(make([]byte, MaxBlobSize))); err != nil || size != MaxBlobSize {
+		t.Fatalf("expected a blob of %d bytes, got %d: %v", MaxBlobSize, size, err)
+	}
+	if _, _, err := store.Put(bytes.NewReader(make([]byte, MaxBlobSize+1))); err == nil {
+		t.Fatal("stored a blob above the limit")
+	}
+}
+
+func request(handler http.Handler, method string, path string, authorization string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	store, close := newTestStore(t)
+	defer close()
+	handler := store.Handler(token)
+
+	for _, authorization := range []string{"", token, "Bearer wrong", "Basic " + token, "bearer " + token} {
+		if w := request(handler, http.MethodPost, BlobsPath, authorization, []byte("evidence")); w.Code != http.StatusUnauthorized {
+			t.Fatalf("authorization %q: expected status %d, got %d", authorization, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	w := request(handler, http.MethodPost, BlobsPath, "Bearer "+token, []byte("evidence"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+
+	var result PutResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	// blobs are served without a token, as untrusted content
+	w = request(handler, http.MethodGet, BlobsPath+result.Hash, "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "evidence" {
+		t.Fatalf("expected the blob, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("Content-Type") != "application/octet-stream" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("blob is served as %q", w.Header().Get("Content-Type"))
+	}
+
+	missing := sha256.Sum256([]byte("missing"))
+	if w := request(handler, http.MethodGet, BlobsPath+hex.EncodeToString(missing[:]), "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing blob: expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := request(handler, http.MethodGet, BlobsPath+"not-a-hash", "", nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid hash: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if w := request(handler, http.MethodPost, BlobsPath, "Bearer "+token, make([]byte, MaxBlobSize+1)); w.Code != http.StatusBadRequest {
+		t.Fatalf("blob above the limit: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if w := request(store.Handler(""), http.MethodPost, BlobsPath, "Bearer ", []byte("evidence")); w.Code != http.StatusUnauthorized {
+		t.Fatalf("empty token: expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,93 @@
+package blobstore
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// BlobsPath is the path of the blob endpoints
+const BlobsPath = "/blobs/"
+
+// PutResult is the response of an upload
+type PutResult struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// Handler return an HTTP handler of the store, POST /blobs/ upload a blob
+// and return its hash, GET /blobs/<hash> serve a blob checked against its hash.
+// Uploads need the token as a bearer token, they are refused when it is empty.
+func (s *Store) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(BlobsPath, func(w http.ResponseWriter, r *http.Request) {
+		hash := strings.TrimPrefix(r.URL.Path, BlobsPath)
+
+		switch {
+		case r.Method == http.MethodPost && hash == "":
+			if !authorized(r, token) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			s.servePut(w, r)
+		case (r.Method == http.MethodGet || r.Method == http.MethodHead) && hash != "":
+			s.serveGet(w, r, hash)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	return mux
+}
+
+// authorized report whether a request carries the upload token as a bearer token
+func authorized(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+
+	given := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (s *Store) servePut(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	hash, size, err := s.Put(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(PutResult{Hash: hash, Size: size})
+}
+
+func (s *Store) serveGet(w http.ResponseWriter, r *http.Request, hash string) {
+	if !validHash(hash) {
+		http.Error(w, "invalid hash", http.StatusBadRequest)
+		return
+	}
+
+	content, err := s.Get(hash)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// blobs are untrusted uploads, browsers must not render them as HTML
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+hash+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Write(content)
+}
@@ -12,4 +12,11 @@ const (
 	CodeTypeDuplicateNonce
 	CodeTypeEmptyMethod
 	CodeTypeInvalidMethod
+	CodeTypeInvalidChainID
+	CodeTypeUnsupportedVersion
+	CodeTypeInvalidParams
+	CodeTypeRevokedKey
+	CodeTypeNotFound
+	CodeTypeMissingPrerequisite
+	CodeTypeForgotten
 )
@@ -0,0 +1,288 @@
+package credential
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/abci/types"
+
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/protocol"
+)
+
+const (
+	// ContextV1 is the JSON-LD context of W3C verifiable credentials
+	ContextV1 = "https://www.w3.org/2018/credentials/v1"
+
+	// TypeVerifiableCredential is the base type of every credential
+	TypeVerifiableCredential = "VerifiableCredential"
+
+	// TypeBadgeCredential is the type of a badge credential
+	TypeBadgeCredential = "SitcomBadgeCredential"
+
+	// ProofType is the type of a proof by the issuer's signature of the
+	// transaction which gave the badge, as recorded by the chain
+	ProofType = "SitcomchainTransactionSignature"
+
+	// ProofPurpose is the purpose of the proof, the issuer asserts the badge
+	ProofPurpose = "assertionMethod"
+)
+
+// Chain is a read access to a sitcomchain node, SitcomApplication
+// implements it and an RPC client can with abci_query
+type Chain interface {
+	Query(req types.RequestQuery) types.ResponseQuery
+}
+
+// Subject is the student a badge is given to
+type Subject struct {
+	ID           string `json:"id"`
+	StudentID    string `json:"studentId"`
+	CompetenceID uint32 `json:"competenceId"`
+	Semester     uint32 `json:"semester"`
+}
+
+// Proof reference the issuer's key and the transaction which gave the badge.
+// It carries only the badge's own payload, and when that payload is an item
+// of a batch or multi-message, the merkle path from it up to the transaction,
+// so the other items of the transaction stay hidden. The issuer's signature
+// is over the sign bytes computed from the payload and the path.
+type Proof struct {
+	Type               string           `json:"type"`
+	Created            string           `json:"created"`
+	ProofPurpose       string           `json:"proofPurpose"`
+	VerificationMethod string           `json:"verificationMethod"`
+	ChainID            string           `json:"chainId"`
+	Height             int64            `json:"height"`
+	TxHash             string           `json:"txHash"`
+	Payload            *protoTm.Payload `json:"payload"`
+	Path               []protocol.Step  `json:"path,omitempty"`
+	KeyType            string           `json:"keyType,omitempty"`
+	Signature          []byte           `json:"signature"`
+}
+
+// Credential is a badge rendered as a W3C verifiable credential
+type Credential struct {
+	Context           []string `json:"@context"`
+	ID                string   `json:"id"`
+	Type              []string `json:"type"`
+	Issuer            string   `json:"issuer"`
+	IssuanceDate      string   `json:"issuanceDate"`
+	CredentialSubject Subject  `json:"credentialSubject"`
+	Proof             Proof    `json:"proof"`
+}
+
+// Issuance is a badge as recorded on chain
+type Issuance struct {
+	ChainID      string
+	StudentID    string
+	CompetenceID uint32
+	Semester     uint32
+	Identity     string
+	PublicKey    []byte
+	Height       int64
+	Time         int64
+	TxHash       string
+	Payload      *protoTm.Payload
+	Path         []protocol.Step
+	KeyType      string
+	Signature    []byte
+}
+
+// TxHash return the hash of a transaction as Tendermint does, so the
+// transaction and its block proof can be fetched with the node's /tx
+func TxHash(tx []byte) string {
+	hashed := sha256.Sum256(tx)
+	return strings.ToUpper(hex.EncodeToString(hashed[:]))
+}
+
+// credentialID return the ID of the credential of a badge
+func credentialID(chainID string, badgeKey []byte) string {
+	return fmt.Sprintf("urn:sitcomchain:%s:badge:%x", chainID, sha256.Sum256(badgeKey))
+}
+
+// issuanceDate return the date of an issuance at a block time
+func issuanceDate(blockTime int64) string {
+	return time.Unix(blockTime, 0).UTC().Format(time.RFC3339)
+}
+
+// IssuerID return the DID of an issuer, its registered identity
+// or its public key when it has none
+func IssuerID(chainID string, identity string, publicKey []byte) string {
+	if identity != "" {
+		return fmt.Sprintf("did:sitcomchain:%s:%s", chainID, identity)
+	}
+
+	return fmt.Sprintf("did:sitcomchain:%s:key:%x", chainID, publicKey)
+}
+
+// New render an issuance as a verifiable credential
+func New(issuance *Issuance) (*Credential, error) {
+	subject := Subject{
+		ID:           "urn:sitcomchain:student:" + issuance.StudentID,
+		StudentID:    issuance.StudentID,
+		CompetenceID: issuance.CompetenceID,
+		Semester:     issuance.Semester,
+	}
+
+	key, err := protocol.BadgeKey(subject.StudentID, subject.CompetenceID, subject.Semester)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := IssuerID(issuance.ChainID, issuance.Identity, issuance.PublicKey)
+	issued := issuanceDate(issuance.Time)
+
+	return &Credential{
+		Context:           []string{ContextV1},
+		ID:                credentialID(issuance.ChainID, key),
+		Type:              []string{TypeVerifiableCredential, TypeBadgeCredential},
+		Issuer:            issuer,
+		IssuanceDate:      issued,
+		CredentialSubject: subject,
+		Proof: Proof{
+			Type:               ProofType,
+			Created:            issued,
+			ProofPurpose:       ProofPurpose,
+			VerificationMethod: fmt.Sprintf("%s#%x", issuer, issuance.PublicKey),
+			ChainID:            issuance.ChainID,
+			Height:             issuance.Height,
+			TxHash:             issuance.TxHash,
+			Payload:            issuance.Payload,
+			Path:               issuance.Path,
+			KeyType:            issuance.KeyType,
+			Signature:          issuance.Signature,
+		},
+	}, nil
+}
+
+// verification is the result of the chain's /verify_badge query
+type verification struct {
+	Height    int64  `json:"height"`
+	Time      int64  `json:"time"`
+	TxHash    string `json:"tx_hash"`
+	SignBytes []byte `json:"sign_bytes"`
+	Valid     bool   `json:"valid"`
+	Signers   []struct {
+		PublicKey []byte `json:"public_key"`
+		Identity  string `json:"identity"`
+	} `json:"signers"`
+}
+
+// checkPayload check that the payload in a proof gives the badge: a GiveBadge
+// must be the badge itself, other payloads which name a student must name
+// the badge's student
+func checkPayload(payload *protoTm.Payload, subject *Subject, key []byte) error {
+	var params struct {
+		StudentID    string `json:"student_id"`
+		CompetenceID uint32 `json:"competence_id"`
+		Semester     uint32 `json:"semester"`
+	}
+	if err := json.Unmarshal(payload.Params, &params); err != nil {
+		return fmt.Errorf("invalid proof payload: %v", err)
+	}
+
+	if payload.Method == protocol.GiveBadgeMethod {
+		given, err := protocol.BadgeKey(params.StudentID, params.CompetenceID, params.Semester)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(given, key) {
+			return fmt.Errorf("proof payload gives badge %s, not %s", given, key)
+		}
+		return nil
+	}
+
+	if params.StudentID != "" && params.StudentID != subject.StudentID {
+		return fmt.Errorf("proof payload is for student %s", params.StudentID)
+	}
+
+	return nil
+}
+
+// Verify check a credential against the chain: the sign bytes computed from
+// the payload and path of the proof must be those of the transaction the chain
+// recorded for the badge at the proof's height, with the recorded date, its
+// signatures must still be valid and the issuer must be one of its signers
+// whose signature over those sign bytes the proof carries
+func Verify(vc *Credential, chain Chain) error {
+	if len(vc.Context) == 0 || vc.Context[0] != ContextV1 {
+		return fmt.Errorf("credential context must start with %s", ContextV1)
+	}
+
+	if len(vc.Type) != 2 || vc.Type[0] != TypeVerifiableCredential || vc.Type[1] != TypeBadgeCredential {
+		return fmt.Errorf("credential is not a %s", TypeBadgeCredential)
+	}
+
+	if vc.Proof.Type != ProofType {
+		return fmt.Errorf("unsupported proof type %s", vc.Proof.Type)
+	}
+
+	digest, err := protocol.Digest(vc.Proof.Payload, vc.Proof.Path)
+	if err != nil {
+		return fmt.Errorf("invalid proof path: %v", err)
+	}
+
+	top := vc.Proof.Payload
+	if len(vc.Proof.Path) > 0 {
+		top = vc.Proof.Path[len(vc.Proof.Path)-1].Payload
+	}
+	if top.ChainId != vc.Proof.ChainID {
+		return fmt.Errorf("proof transaction is not for chain %s", vc.Proof.ChainID)
+	}
+
+	subject := vc.CredentialSubject
+	key, err := protocol.BadgeKey(subject.StudentID, subject.CompetenceID, subject.Semester)
+	if err != nil {
+		return err
+	}
+
+	if vc.ID != credentialID(vc.Proof.ChainID, key) {
+		return fmt.Errorf("credential ID does not match badge %s", key)
+	}
+
+	if err := checkPayload(vc.Proof.Payload, &subject, key); err != nil {
+		return err
+	}
+
+	res := chain.Query(types.RequestQuery{Path: "/verify_badge", Data: key})
+	if res.Code != 0 || len(res.Value) == 0 {
+		return fmt.Errorf("badge %s is not on chain: %s", key, res.Log)
+	}
+
+	var result verification
+	if err := json.Unmarshal(res.Value, &result); err != nil {
+		return err
+	}
+
+	if result.Height != vc.Proof.Height || result.TxHash != vc.Proof.TxHash || !bytes.Equal(result.SignBytes, digest) {
+		return fmt.Errorf("badge %s was not issued by the proof transaction", key)
+	}
+
+	if issued := issuanceDate(result.Time); vc.IssuanceDate != issued || vc.Proof.Created != issued {
+		return fmt.Errorf("badge %s was issued on %s", key, issued)
+	}
+
+	if !result.Valid {
+		return fmt.Errorf("issuance of badge %s is no longer valid", key)
+	}
+
+	for _, signer := range result.Signers {
+		issuer := IssuerID(vc.Proof.ChainID, signer.Identity, signer.PublicKey)
+		if issuer != vc.Issuer || vc.Proof.VerificationMethod != fmt.Sprintf("%s#%x", issuer, signer.PublicKey) {
+			continue
+		}
+
+		if !protocol.VerifySignature(vc.Proof.KeyType, signer.PublicKey, digest, vc.Proof.Signature) {
+			return fmt.Errorf("proof is not signed by issuer %s", vc.Issuer)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("issuer %s did not sign badge %s", vc.Issuer, key)
+}
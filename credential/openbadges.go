@@ -0,0 +1,134 @@
+package credential
+
+import (
+	"fmt"
+)
+
+const (
+	// ContextOpenBadgesV3 is the JSON-LD context of Open Badges 3.0
+	ContextOpenBadgesV3 = "https://purl.imsglobal.org/spec/ob/v3p0/context.json"
+
+	// TypeOpenBadgeCredential is the type of an Open Badges 3.0 assertion
+	TypeOpenBadgeCredential = "OpenBadgeCredential"
+)
+
+// Criteria describe how an achievement is earned
+type Criteria struct {
+	Narrative string `json:"narrative,omitempty"`
+}
+
+// Image is the image of an achievement
+type Image struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Evidence is a file attested with a badge, its hash is the sha256 of the
+// file in lowercase hex and is kept even when the file has a URI
+type Evidence struct {
+	ID   string   `json:"id"`
+	Type []string `json:"type"`
+	Hash string   `json:"hash"`
+	Size int64    `json:"size,omitempty"`
+}
+
+// Achievement is a competence as an Open Badges achievement
+type Achievement struct {
+	ID              string   `json:"id"`
+	Type            []string `json:"type"`
+	AchievementType string   `json:"achievementType,omitempty"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	Criteria        Criteria `json:"criteria"`
+	Image           *Image   `json:"image,omitempty"`
+}
+
+// Profile is the issuer of an Open Badges assertion
+type Profile struct {
+	ID          string   `json:"id"`
+	Type        []string `json:"type"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url,omitempty"`
+	Email       string   `json:"email,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// AchievementSubject is the student who earned an achievement
+type AchievementSubject struct {
+	ID          string      `json:"id"`
+	Type        []string    `json:"type"`
+	Narrative   string      `json:"narrative,omitempty"`
+	Achievement Achievement `json:"achievement"`
+}
+
+// OpenBadgeCredential is a badge as an Open Badges 3.0 assertion
+type OpenBadgeCredential struct {
+	Context           []string           `json:"@context"`
+	ID                string             `json:"id"`
+	Type              []string           `json:"type"`
+	Issuer            Profile            `json:"issuer"`
+	IssuanceDate      string             `json:"issuanceDate"`
+	Name              string             `json:"name"`
+	CredentialSubject AchievementSubject `json:"credentialSubject"`
+	Evidence          []Evidence         `json:"evidence,omitempty"`
+	Proof             Proof              `json:"proof"`
+}
+
+// AchievementID return the ID of the achievement of a competence
+func AchievementID(chainID string, competenceID uint32) string {
+	return fmt.Sprintf("urn:sitcomchain:%s:competence:%d", chainID, competenceID)
+}
+
+// contentID return the URI of a file when it has one or else
+// a URN of its sha256 to be fetched from a content store
+func contentID(uri string, hash string) string {
+	if uri != "" {
+		return uri
+	}
+
+	return "urn:sha256:" + hash
+}
+
+// NewImage return the image of an achievement
+func NewImage(uri string, hash string) *Image {
+	if uri == "" && hash == "" {
+		return nil
+	}
+
+	return &Image{ID: contentID(uri, hash), Type: "Image"}
+}
+
+// NewEvidence return a file attested with a badge
+func NewEvidence(uri string, hash string, size int64) Evidence {
+	return Evidence{
+		ID:   contentID(uri, hash),
+		Type: []string{"Evidence"},
+		Hash: hash,
+		Size: size,
+	}
+}
+
+// NewOpenBadge render a verifiable credential of a badge as an Open Badges
+// 3.0 assertion, it keeps the credential's ID, issuer and proof
+func NewOpenBadge(vc *Credential, achievement Achievement, issuer Profile, narrative string, evidence []Evidence) *OpenBadgeCredential {
+	achievement.Type = []string{"Achievement"}
+	issuer.ID = vc.Issuer
+	issuer.Type = []string{"Profile"}
+
+	return &OpenBadgeCredential{
+		Context:      []string{ContextV1, ContextOpenBadgesV3},
+		ID:           vc.ID,
+		Type:         []string{TypeVerifiableCredential, TypeOpenBadgeCredential},
+		Issuer:       issuer,
+		IssuanceDate: vc.IssuanceDate,
+		Name:         achievement.Name,
+		CredentialSubject: AchievementSubject{
+			ID:          vc.CredentialSubject.ID,
+			Type:        []string{"AchievementSubject"},
+			Narrative:   narrative,
+			Achievement: achievement,
+		},
+		Evidence: evidence,
+		Proof:    vc.Proof,
+	}
+}
@@ -0,0 +1,131 @@
+package disclosure
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tendermint/tendermint/crypto/merkle"
+)
+
+const leafDomain = "sitcomchain/portfolio:"
+
+// Badge is a disclosed badge key with its salt and its proof of inclusion in the portfolio
+type Badge struct {
+	BadgeKey string             `json:"badge_key"`
+	Salt     []byte             `json:"salt"`
+	Proof    merkle.SimpleProof `json:"proof"`
+}
+
+// Package is a disclosure of chosen badges of a student's portfolio. Other
+// leaves are salted, so the proofs reveal no other badge key, though they
+// reveal how many leaves the portfolio has.
+type Package struct {
+	StudentID string  `json:"student_id"`
+	Root      []byte  `json:"root"`
+	Badges    []Badge `json:"badges"`
+}
+
+// Salt return the salt of a badge's leaf, derived from the student's
+// disclosure secret so the student can rebuild every leaf
+func Salt(secret, badgeKey []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(badgeKey)
+	return mac.Sum(nil)
+}
+
+// Leaf return the salted commitment of a badge which is appended to the
+// student's portfolio, it does not reveal the badge key without the salt
+func Leaf(salt, badgeKey []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte(leafDomain))
+	hasher.Write([]byte(fmt.Sprintf("%d:", len(salt))))
+	hasher.Write(salt)
+	hasher.Write(badgeKey)
+	return hasher.Sum(nil)
+}
+
+// LeafHex return the leaf of a badge in hex, as given in portfolio_leaf
+func LeafHex(secret, badgeKey []byte) string {
+	return hex.EncodeToString(Leaf(Salt(secret, badgeKey), badgeKey))
+}
+
+// Root return the merkle root of a portfolio, a list of leaves in issuance order
+func Root(leaves [][]byte) []byte {
+	return merkle.SimpleHashFromByteSlices(leaves)
+}
+
+// New create a disclosure package of badges at the chosen indexes of a
+// portfolio, given as the student's badge keys in the order of their leaves
+func New(studentID string, secret []byte, badgeKeys [][]byte, chosen []int) (*Package, error) {
+	leaves := make([][]byte, len(badgeKeys))
+	for i, badgeKey := range badgeKeys {
+		leaves[i] = Leaf(Salt(secret, badgeKey), badgeKey)
+	}
+	root, proofs := merkle.SimpleProofsFromByteSlices(leaves)
+
+	pkg := Package{
+		StudentID: studentID,
+		Root:      root,
+		Badges:    make([]Badge, 0, len(chosen)),
+	}
+
+	for _, index := range chosen {
+		if index < 0 || index >= len(badgeKeys) {
+			return nil, fmt.Errorf("badge index %d out of range", index)
+		}
+
+		pkg.Badges = append(pkg.Badges, Badge{
+			BadgeKey: string(badgeKeys[index]),
+			Salt:     Salt(secret, badgeKeys[index]),
+			Proof:    *proofs[index],
+		})
+	}
+
+	return &pkg, nil
+}
+
+// Size return the number of leaves of the portfolio the package was built
+// from, the size to read the portfolio root at
+func (pkg *Package) Size() int {
+	if len(pkg.Badges) == 0 {
+		return 0
+	}
+
+	return pkg.Badges[0].Proof.Total
+}
+
+// Verify check the package against a portfolio root read from the chain for
+// the student at the package's size, every disclosed badge must be included
+// in the portfolio and belong to the student
+func (pkg *Package) Verify(root []byte) error {
+	if !bytes.Equal(pkg.Root, root) {
+		return fmt.Errorf("package root does not match portfolio root")
+	}
+
+	for _, badge := range pkg.Badges {
+		if badge.Proof.Total != pkg.Size() {
+			return fmt.Errorf("proofs of the package are not for the same portfolio")
+		}
+
+		if err := badge.Proof.Verify(root, Leaf(badge.Salt, []byte(badge.BadgeKey))); err != nil {
+			return fmt.Errorf("badge %s: %v", badge.BadgeKey, err)
+		}
+
+		var key struct {
+			StudentID string `json:"student_id"`
+		}
+		if err := json.Unmarshal([]byte(badge.BadgeKey), &key); err != nil {
+			return fmt.Errorf("badge %s: %v", badge.BadgeKey, err)
+		}
+
+		if key.StudentID != pkg.StudentID {
+			return fmt.Errorf("badge %s does not belong to student %s", badge.BadgeKey, pkg.StudentID)
+		}
+	}
+
+	return nil
+}
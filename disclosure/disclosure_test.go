@@ -0,0 +1,121 @@
+package disclosure
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+var secret = []byte("a secret of the student")
+
+func badgeKeys(studentID string, n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf(`{"competence_id":%d,"semester":1,"student_id":"%s"}`, i+1, studentID))
+	}
+	return keys
+}
+
+func leaves(keys [][]byte) [][]byte {
+	items := make([][]byte, len(keys))
+	for i, key := range keys {
+		items[i] = Leaf(Salt(secret, key), key)
+	}
+	return items
+}
+
+func TestPackageVerify(t *testing.T) {
+	keys := badgeKeys("s1", 5)
+	root := Root(leaves(keys))
+
+	pkg, err := New("s1", secret, keys, []int{1, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pkg.Size() != 5 {
+		t.Fatalf("expected package of 5 leaves, got %d", pkg.Size())
+	}
+	if err := pkg.Verify(root); err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg.Badges) != 2 || pkg.Badges[0].BadgeKey != string(keys[1]) || pkg.Badges[1].BadgeKey != string(keys[3]) {
+		t.Fatalf("package holds other badges than chosen: %+v", pkg.Badges)
+	}
+}
+
+func TestPackageRejectsTampering(t *testing.T) {
+	keys := badgeKeys("s1", 4)
+	root := Root(leaves(keys))
+
+	tamper := map[string]func(pkg *Package){
+		"badge key": func(pkg *Package) { pkg.Badges[0].BadgeKey = string(keys[0]) },
+		"salt":      func(pkg *Package) { pkg.Badges[0].Salt = Salt([]byte("another secret"), keys[2]) },
+		"student":   func(pkg *Package) { pkg.StudentID = "s2" },
+		"proof":     func(pkg *Package) { pkg.Badges[0].Proof.Index = 1 },
+	}
+
+	for name, change := range tamper {
+		pkg, err := New("s1", secret, keys, []int{2})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		change(pkg)
+		if err := pkg.Verify(root); err == nil {
+			t.Errorf("package with a tampered %s verifies", name)
+		}
+	}
+
+	pkg, err := New("s1", secret, keys, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pkg.Verify(Root(leaves(keys[:3]))); err == nil {
+		t.Error("package verifies against the root of another size")
+	}
+}
+
+func TestPackageRejectsAnotherStudentsBadge(t *testing.T) {
+	keys := append(badgeKeys("s1", 2), badgeKeys("s2", 1)...)
+	root := Root(leaves(keys))
+
+	pkg, err := New("s1", secret, keys, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pkg.Verify(root); err == nil {
+		t.Fatal("package discloses a badge of another student")
+	}
+}
+
+func TestRootOfPrefixIsStable(t *testing.T) {
+	keys := badgeKeys("s1", 6)
+
+	pkg, err := New("s1", secret, keys[:4], []int{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(pkg.Root, Root(leaves(keys)[:4])) {
+		t.Fatal("root of the first leaves changed as the portfolio grew")
+	}
+}
+
+func TestLeafIsSalted(t *testing.T) {
+	key := badgeKeys("s1", 1)[0]
+
+	if bytes.Equal(Leaf(Salt(secret, key), key), Leaf(Salt([]byte("another secret"), key), key)) {
+		t.Fatal("leaves of the same badge with different secrets are equal")
+	}
+	if LeafHex(secret, key) == LeafHex(secret, badgeKeys("s1", 2)[1]) {
+		t.Fatal("leaves of different badges are equal")
+	}
+}
+
+func TestNewRejectsInvalidIndex(t *testing.T) {
+	if _, err := New("s1", secret, badgeKeys("s1", 2), []int{2}); err == nil {
+		t.Fatal("package of an index out of range")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/importer"
+)
+
+// runImport run the import subcommand, it gives badge records of a CSV
+// in batches and writes a reconciliation report
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	node := flags.String("node", "http://localhost:26657", "RPC address of a node")
+	chainID := flags.String("chain-id", "", "chain ID, read from the node when empty")
+	keyFiles := flags.String("keys", "", "comma separated files of base64 ed25519 private keys to sign with")
+	batchSize := flags.Int("batch", 100, "badges per transaction")
+	statePath := flags.String("state", "import.state", "file recording imported batches, to resume an interrupted import")
+	reportPath := flags.String("report", "import-report.csv", "file of the reconciliation report")
+	dryRun := flags.Bool("dry-run", false, "validate without broadcasting")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s import [flags] records.csv\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	keys := make([]ed25519.PrivateKey, 0)
+	for _, path := range strings.Split(*keyFiles, ",") {
+		if path == "" {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil || len(key) != ed25519.PrivateKeySize {
+			fmt.Fprintf(os.Stderr, "%s is not a base64 ed25519 private key\n", path)
+			return 1
+		}
+		keys = append(keys, ed25519.PrivateKey(key))
+	}
+	if len(keys) == 0 && !*dryRun {
+		fmt.Fprintln(os.Stderr, "-keys is required")
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	rows, err := importer.ReadCSV(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	im, err := importer.New(importer.NewClient(*node), importer.Config{
+		ChainID:   *chainID,
+		Keys:      keys,
+		BatchSize: *batchSize,
+		StatePath: *statePath,
+		Progress:  os.Stderr,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := im.Validate(rows); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	importErr := error(nil)
+	if !*dryRun {
+		importErr = im.Import(rows)
+	}
+
+	report, err := os.Create(*reportPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer report.Close()
+	if err := importer.WriteReport(report, rows); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	summary := importer.Summary(rows)
+	fmt.Fprintf(os.Stderr, "%d rows: %d imported, %d already on chain, %d invalid, %d failed, %d pending; report in %s\n",
+		len(rows), summary[importer.StatusImported], summary[importer.StatusExists], summary[importer.StatusInvalid], summary[importer.StatusFailed], summary[""], *reportPath)
+
+	if importErr != nil {
+		fmt.Fprintln(os.Stderr, importErr)
+		return 1
+	}
+	if summary[importer.StatusInvalid] > 0 || summary[importer.StatusFailed] > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,439 @@
+package importer
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/app"
+	"github.com/saguywalker/sitcomchain/disclosure"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/student"
+)
+
+const (
+	// StatusImported is a row given in a transaction of this import
+	StatusImported = "imported"
+
+	// StatusExists is a row whose badge was already on chain
+	StatusExists = "exists"
+
+	// StatusInvalid is a row rejected before broadcasting
+	StatusInvalid = "invalid"
+
+	// StatusFailed is a row of a batch rejected by the chain
+	StatusFailed = "failed"
+)
+
+// Row is a badge record read from a line of the CSV
+type Row struct {
+	Line   int
+	Badge  app.GiveBadge
+	Status string
+	Detail string
+	TxHash string
+}
+
+// Config of an import
+type Config struct {
+	ChainID   string
+	Keys      []ed25519.PrivateKey
+	BatchSize int
+	StatePath string
+	Progress  io.Writer
+}
+
+// Importer validate badge records against the chain and give them in batches
+type Importer struct {
+	client      *Client
+	config      Config
+	competences map[uint32]*app.Competence
+	semesters   map[uint32]*app.Semester
+	state       map[string]string
+}
+
+// New return an importer which loads the competence catalog and the
+// semester registry of the chain, and the batches done by a previous run
+func New(client *Client, config Config) (*Importer, error) {
+	if config.BatchSize <= 0 || config.BatchSize > app.MaxBatchEntries {
+		return nil, fmt.Errorf("batch size must be 1 to %d", app.MaxBatchEntries)
+	}
+
+	if config.ChainID == "" {
+		chainID, err := client.ChainID()
+		if err != nil {
+			return nil, err
+		}
+		config.ChainID = chainID
+	}
+
+	im := Importer{
+		client:      client,
+		config:      config,
+		competences: make(map[uint32]*app.Competence),
+		semesters:   make(map[uint32]*app.Semester),
+		state:       make(map[string]string),
+	}
+
+	value, err := client.Query("/competences", nil)
+	if err != nil {
+		return nil, err
+	}
+	var competences []*app.Competence
+	if err := json.Unmarshal(value, &competences); err != nil {
+		return nil, err
+	}
+	for _, competence := range competences {
+		im.competences[competence.ID] = competence
+	}
+
+	value, err = client.Query("/semesters", nil)
+	if err != nil {
+		return nil, err
+	}
+	var semesters []*app.Semester
+	if err := json.Unmarshal(value, &semesters); err != nil {
+		return nil, err
+	}
+	for _, semester := range semesters {
+		im.semesters[semester.ID] = semester
+	}
+
+	if config.StatePath != "" {
+		value, err := ioutil.ReadFile(config.StatePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(value, &im.state); err != nil {
+				return nil, fmt.Errorf("invalid state file %s: %v", config.StatePath, err)
+			}
+		}
+	}
+
+	return &im, nil
+}
+
+// ReadCSV read badge records from a CSV with a header of student_id,
+// competence_id and semester, and optional secret and narrative columns.
+// With a secret (in hex), student_id is the plaintext student ID and is
+// committed and the badge's portfolio leaf is salted with the secret,
+// otherwise it must already be a commitment.
+func ReadCSV(r io.Reader) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"student_id", "competence_id", "semester"} {
+		if _, exists := columns[name]; !exists {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, exists := columns[name]; exists && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := make([]*Row, 0)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := Row{Line: line}
+		rows = append(rows, &row)
+
+		competenceID, err := strconv.ParseUint(field(record, "competence_id"), 10, 32)
+		if err != nil {
+			row.Status, row.Detail = StatusInvalid, "invalid competence_id"
+			continue
+		}
+
+		semester, err := strconv.ParseUint(field(record, "semester"), 10, 32)
+		if err != nil {
+			row.Status, row.Detail = StatusInvalid, "invalid semester"
+			continue
+		}
+
+		studentID := field(record, "student_id")
+		var decoded []byte
+		if secret := field(record, "secret"); secret != "" {
+			decoded, err = hex.DecodeString(secret)
+			if err != nil {
+				row.Status, row.Detail = StatusInvalid, "secret must be in hex"
+				continue
+			}
+			if studentID, err = student.Commit(studentID, decoded); err != nil {
+				row.Status, row.Detail = StatusInvalid, err.Error()
+				continue
+			}
+		}
+
+		row.Badge = app.GiveBadge{
+			StudentID:    studentID,
+			CompetenceID: uint32(competenceID),
+			Semester:     uint32(semester),
+			Narrative:    field(record, "narrative"),
+		}
+
+		if decoded != nil {
+			badgeKey, err := app.BadgeKey(&row.Badge)
+			if err != nil {
+				row.Status, row.Detail = StatusInvalid, err.Error()
+				continue
+			}
+			row.Badge.PortfolioLeaf = disclosure.LeafHex(decoded, badgeKey)
+		}
+	}
+
+	return rows, nil
+}
+
+// Validate check rows against the competence catalog and semester registry,
+// and mark rows whose badge is already on chain or repeated in the file
+func (im *Importer) Validate(rows []*Row) error {
+	seen := make(map[string]int)
+	for _, row := range rows {
+		if row.Status != "" {
+			continue
+		}
+
+		if !student.IsCommitment(row.Badge.StudentID) {
+			row.Status, row.Detail = StatusInvalid, "student_id must be a commitment or come with a secret"
+			continue
+		}
+
+		competence, exists := im.competences[row.Badge.CompetenceID]
+		if !exists {
+			row.Status, row.Detail = StatusInvalid, fmt.Sprintf("competence %d not found", row.Badge.CompetenceID)
+			continue
+		}
+		if competence.Deprecated {
+			row.Status, row.Detail = StatusInvalid, fmt.Sprintf("competence %d is deprecated", row.Badge.CompetenceID)
+			continue
+		}
+
+		if _, exists := im.semesters[row.Badge.Semester]; !exists {
+			row.Status, row.Detail = StatusInvalid, fmt.Sprintf("semester %d not found", row.Badge.Semester)
+			continue
+		}
+
+		badgeKey, err := app.BadgeKey(&row.Badge)
+		if err != nil {
+			return err
+		}
+
+		if line, exists := seen[string(badgeKey)]; exists {
+			row.Status, row.Detail = StatusInvalid, fmt.Sprintf("same badge as line %d", line)
+			continue
+		}
+		seen[string(badgeKey)] = row.Line
+
+		value, err := im.client.Query("", badgeKey)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			row.Status, row.Detail = StatusExists, "badge is already on chain"
+		}
+	}
+
+	return nil
+}
+
+// sign return a signed GiveBadgeBatch transaction
+func (im *Importer) sign(params []byte) ([]byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	payload := &protoTm.Payload{
+		Method:  "GiveBadgeBatch",
+		Params:  params,
+		ChainId: im.config.ChainID,
+		Nonce:   nonce,
+		Version: app.SignVersion,
+	}
+
+	signBytes, err := app.SignBytes(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	txObj := protoTm.Tx{Payload: payload}
+	for _, key := range im.config.Keys {
+		txObj.Signatures = append(txObj.Signatures, &protoTm.Signature{
+			PublicKey: key.Public().(ed25519.PublicKey),
+			Signature: ed25519.Sign(key, signBytes),
+			KeyType:   app.KeyTypeEd25519,
+		})
+	}
+
+	return proto.Marshal(&txObj)
+}
+
+func (im *Importer) saveState() error {
+	if im.config.StatePath == "" {
+		return nil
+	}
+
+	value, err := json.MarshalIndent(im.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(im.config.StatePath, value, 0644)
+}
+
+func (im *Importer) progress(format string, args ...interface{}) {
+	if im.config.Progress != nil {
+		fmt.Fprintf(im.config.Progress, format+"\n", args...)
+	}
+}
+
+// Import give valid rows in batches of signed transactions and wait for each
+// to be committed. A batch done by a previous run with the same state file
+// is skipped, a batch rejected by the chain marks its rows failed.
+func (im *Importer) Import(rows []*Row) error {
+	pending := make([]*Row, 0, len(rows))
+	for _, row := range rows {
+		if row.Status == "" {
+			pending = append(pending, row)
+		}
+	}
+
+	total := (len(pending) + im.config.BatchSize - 1) / im.config.BatchSize
+	for i := 0; i < total; i++ {
+		end := (i + 1) * im.config.BatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[i*im.config.BatchSize : end]
+
+		entries := make([]app.GiveBadge, 0, len(batch))
+		for _, row := range batch {
+			entries = append(entries, row.Badge)
+		}
+
+		params, err := json.Marshal(app.GiveBadgeBatch{Entries: entries})
+		if err != nil {
+			return err
+		}
+		hashed := sha256.Sum256(params)
+		batchID := hex.EncodeToString(hashed[:])
+
+		if txHash, done := im.state[batchID]; done {
+			for _, row := range batch {
+				row.Status, row.TxHash = StatusImported, txHash
+			}
+			im.progress("batch %d/%d: %d badges already imported in %s", i+1, total, len(batch), txHash)
+			continue
+		}
+
+		tx, err := im.sign(params)
+		if err != nil {
+			return err
+		}
+
+		result, err := im.client.Broadcast(tx)
+		if err != nil {
+			return fmt.Errorf("batch %d/%d: %v", i+1, total, err)
+		}
+
+		if result.CheckTx.Code != 0 {
+			for _, row := range batch {
+				row.Status, row.Detail = StatusFailed, result.CheckTx.Log
+			}
+			im.progress("batch %d/%d: rejected: %s", i+1, total, result.CheckTx.Log)
+			continue
+		}
+
+		if result.DeliverTx.Code != 0 {
+			var results []app.BatchResult
+			if err := json.Unmarshal(result.DeliverTx.Data, &results); err != nil {
+				for _, row := range batch {
+					row.Status, row.TxHash, row.Detail = StatusFailed, result.Hash, result.DeliverTx.Log
+				}
+				return fmt.Errorf("batch %d/%d: failed: %s: invalid batch results: %v", i+1, total, result.DeliverTx.Log, err)
+			}
+			for j, row := range batch {
+				row.Status, row.TxHash, row.Detail = StatusFailed, result.Hash, "rolled back with its batch"
+				if j < len(results) && results[j].Code != 0 {
+					row.Detail = results[j].Log
+				}
+			}
+			im.progress("batch %d/%d: failed: %s", i+1, total, result.DeliverTx.Log)
+			continue
+		}
+
+		for _, row := range batch {
+			row.Status, row.TxHash = StatusImported, result.Hash
+		}
+		im.state[batchID] = result.Hash
+		if err := im.saveState(); err != nil {
+			return err
+		}
+		im.progress("batch %d/%d: %d badges imported at height %s in %s", i+1, total, len(batch), result.Height, result.Hash)
+	}
+
+	return nil
+}
+
+// WriteReport write a reconciliation report of every row as CSV
+func WriteReport(w io.Writer, rows []*Row) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"line", "student_id", "competence_id", "semester", "status", "detail", "tx_hash"})
+
+	for _, row := range rows {
+		writer.Write([]string{
+			strconv.Itoa(row.Line),
+			row.Badge.StudentID,
+			strconv.FormatUint(uint64(row.Badge.CompetenceID), 10),
+			strconv.FormatUint(uint64(row.Badge.Semester), 10),
+			row.Status,
+			row.Detail,
+			row.TxHash,
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Summary count rows by status
+func Summary(rows []*Row) map[string]int {
+	summary := make(map[string]int)
+	for _, row := range rows {
+		summary[row.Status]++
+	}
+
+	return summary
+}
@@ -0,0 +1,373 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"github.com/tendermint/tendermint/abci/types"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/saguywalker/sitcomchain/app"
+	"github.com/saguywalker/sitcomchain/disclosure"
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	"github.com/saguywalker/sitcomchain/student"
+)
+
+const testChainID = "test-chain"
+
+var secret = "00112233445566778899aabbccddeeff"
+
+// node serve the JSON-RPC of a Tendermint node in front of an application,
+// every broadcast transaction is committed in a block of its own
+type node struct {
+	t      *testing.T
+	dir    string
+	app    *app.SitcomApplication
+	server *httptest.Server
+	admin  ed25519.PrivateKey
+	nonce  int
+
+	// corrupt replace the data of failed transactions
+	corrupt bool
+}
+
+func newNode(t *testing.T) *node {
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	n := node{t: t, dir: dir, app: app.NewSitcomApp(filepath.Join(dir, "data"), logger)}
+	n.app.InitChain(types.RequestInitChain{ChainId: testChainID})
+	n.server = httptest.NewServer(http.HandlerFunc(n.serve))
+
+	publicKey, admin, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.admin = admin
+	n.deliver("AddNewService", []byte("sitcompetence:"+base64.StdEncoding.EncodeToString(publicKey)))
+
+	n.deliverJSON("CreateCompetence", app.Competence{ID: 1, Name: "Teamwork"})
+	n.deliverJSON("CreateCompetence", app.Competence{ID: 2, Name: "Leadership"})
+	n.deliverJSON("CreateCompetence", app.Competence{ID: 3, Name: "Punch cards"})
+	n.deliverJSON("DeprecateCompetence", map[string]uint32{"id": 3})
+	n.deliverJSON("CreateSemester", app.Semester{ID: 1, Name: "1/2019", Start: 0, End: 1 << 40})
+
+	return &n
+}
+
+func (n *node) close() {
+	n.server.Close()
+	os.RemoveAll(n.dir)
+}
+
+func (n *node) client() *Client {
+	return NewClient(n.server.URL)
+}
+
+func (n *node) deliverJSON(method string, params interface{}) {
+	n.t.Helper()
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		n.t.Fatal(err)
+	}
+	n.deliver(method, encoded)
+}
+
+// deliver commit a transaction signed by sitcompetence which must succeed
+func (n *node) deliver(method string, params []byte) {
+	n.t.Helper()
+
+	n.nonce++
+	payload := &protoTm.Payload{
+		Method:  method,
+		Params:  params,
+		ChainId: testChainID,
+		Nonce:   []byte(fmt.Sprintf("node-%d", n.nonce)),
+		Version: app.SignVersion,
+	}
+
+	signBytes, err := app.SignBytes(payload)
+	if err != nil {
+		n.t.Fatal(err)
+	}
+
+	tx, err := proto.Marshal(&protoTm.Tx{
+		Payload: payload,
+		Signatures: []*protoTm.Signature{{
+			PublicKey: n.admin.Public().(ed25519.PublicKey),
+			Signature: ed25519.Sign(n.admin, signBytes),
+		}},
+	})
+	if err != nil {
+		n.t.Fatal(err)
+	}
+
+	if res := n.app.DeliverTx(types.RequestDeliverTx{Tx: tx}); res.Code != 0 {
+		n.t.Fatalf("%s: %s", method, res.Log)
+	}
+}
+
+func (n *node) serve(w http.ResponseWriter, r *http.Request) {
+	var result interface{}
+	query := r.URL.Query()
+
+	switch strings.TrimPrefix(r.URL.Path, "/") {
+	case "status":
+		result = map[string]interface{}{"node_info": map[string]string{"network": testChainID}}
+	case "abci_query":
+		path, err := strconv.Unquote(query.Get("path"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := hex.DecodeString(strings.TrimPrefix(query.Get("data"), "0x"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res := n.app.Query(types.RequestQuery{Path: path, Data: data})
+		result = map[string]interface{}{"response": map[string]interface{}{"code": res.Code, "log": res.Log, "value": res.Value}}
+	case "broadcast_tx_commit":
+		tx, err := hex.DecodeString(strings.TrimPrefix(query.Get("tx"), "0x"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		broadcast := BroadcastResult{Hash: fmt.Sprintf("%X", len(tx)), Height: "1"}
+		checkTx := n.app.CheckTx(types.RequestCheckTx{Tx: tx})
+		broadcast.CheckTx = TxResult{Code: checkTx.Code, Log: checkTx.Log}
+		if checkTx.Code == 0 {
+			deliverTx := n.app.DeliverTx(types.RequestDeliverTx{Tx: tx})
+			broadcast.DeliverTx = TxResult{Code: deliverTx.Code, Log: deliverTx.Log, Data: deliverTx.Data}
+			if n.corrupt && deliverTx.Code != 0 {
+				broadcast.DeliverTx.Data = []byte("not json")
+			}
+			n.app.Commit()
+		}
+		result = broadcast
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]json.RawMessage{"result": encoded})
+}
+
+func commitment(t *testing.T, studentID string) string {
+	decoded, err := hex.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := student.Commit(studentID, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func readCSV(t *testing.T, lines ...string) []*Row {
+	rows, err := ReadCSV(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func statuses(rows []*Row) []string {
+	result := make([]string, len(rows))
+	for i, row := range rows {
+		result[i] = row.Status
+	}
+	return result
+}
+
+func TestReadCSV(t *testing.T) {
+	if _, err := ReadCSV(strings.NewReader("student_id,semester\n1,1")); err == nil {
+		t.Fatal("CSV without a competence_id column read")
+	}
+
+	rows := readCSV(t,
+		"student_id,competence_id,semester,secret,narrative",
+		"6030001,1,1,"+secret+",Led the team",
+		"6030002,x,1,"+secret+",",
+		"6030003,1,1,not hex,",
+		commitment(t, "6030004")+",2,1,,",
+	)
+
+	if rows[0].Status != "" || rows[0].Badge.StudentID != commitment(t, "6030001") || rows[0].Badge.Narrative != "Led the team" {
+		t.Fatalf("unexpected row: %+v", rows[0])
+	}
+	if rows[1].Status != StatusInvalid || rows[2].Status != StatusInvalid {
+		t.Fatalf("expected rows 2 and 3 invalid, got %v", statuses(rows))
+	}
+	if rows[3].Status != "" || rows[3].Badge.PortfolioLeaf != "" {
+		t.Fatalf("unexpected row: %+v", rows[3])
+	}
+
+	badgeKey, err := app.BadgeKey(&rows[0].Badge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := hex.DecodeString(secret)
+	if rows[0].Badge.PortfolioLeaf != disclosure.LeafHex(decoded, badgeKey) {
+		t.Fatal("portfolio leaf of a row with a secret is not salted with the secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	n := newNode(t)
+	defer n.close()
+
+	n.deliverJSON("GiveBadge", app.GiveBadge{StudentID: commitment(t, "6030005"), CompetenceID: 1, Semester: 1})
+
+	rows := readCSV(t,
+		"student_id,competence_id,semester,secret",
+		"6030001,1,1,"+secret,
+		"6030002,9,1,"+secret,
+		"6030003,3,1,"+secret,
+		"6030004,1,9,"+secret,
+		"6030001,1,1,"+secret,
+		"6030005,1,1,"+secret,
+		"6030006,1,1,",
+	)
+
+	im, err := New(n.client(), Config{BatchSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.Validate(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"", StatusInvalid, StatusInvalid, StatusInvalid, StatusInvalid, StatusExists, StatusInvalid}
+	if fmt.Sprint(statuses(rows)) != fmt.Sprint(expected) {
+		t.Fatalf("expected statuses %v, got %v", expected, statuses(rows))
+	}
+}
+
+func TestImportResumes(t *testing.T) {
+	n := newNode(t)
+	defer n.close()
+
+	lines := []string{"student_id,competence_id,semester,secret"}
+	for i := 1; i <= 5; i++ {
+		lines = append(lines, fmt.Sprintf("603000%d,1,1,%s", i, secret))
+	}
+	statePath := filepath.Join(n.dir, "import.state")
+
+	rows := readCSV(t, lines...)
+	im, err := New(n.client(), Config{Keys: []ed25519.PrivateKey{n.admin}, BatchSize: 2, StatePath: statePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.Validate(rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := im.Import(rows); err != nil {
+		t.Fatal(err)
+	}
+	if summary := Summary(rows); summary[StatusImported] != 5 {
+		t.Fatalf("expected 5 rows imported, got %v", summary)
+	}
+
+	// a rerun with the same state skips batches already done
+	rows = readCSV(t, lines...)
+	im, err = New(n.client(), Config{Keys: []ed25519.PrivateKey{n.admin}, BatchSize: 2, StatePath: statePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.Import(rows); err != nil {
+		t.Fatal(err)
+	}
+	if summary := Summary(rows); summary[StatusImported] != 5 {
+		t.Fatalf("expected 5 rows imported, got %v", summary)
+	}
+}
+
+func TestImportFailedBatch(t *testing.T) {
+	n := newNode(t)
+	defer n.close()
+
+	rows := readCSV(t,
+		"student_id,competence_id,semester,secret",
+		"6030001,1,1,"+secret,
+		"6030002,1,1,"+secret,
+		"6030003,1,1,"+secret,
+	)
+
+	im, err := New(n.client(), Config{Keys: []ed25519.PrivateKey{n.admin}, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.Validate(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	// given by someone else after validation, its batch is rolled back
+	n.deliverJSON("GiveBadge", rows[1].Badge)
+
+	if err := im.Import(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{StatusFailed, StatusFailed, StatusImported}
+	if fmt.Sprint(statuses(rows)) != fmt.Sprint(expected) {
+		t.Fatalf("expected statuses %v, got %v", expected, statuses(rows))
+	}
+	if rows[0].Detail != "rolled back with its batch" || !strings.Contains(rows[1].Detail, "already been given") {
+		t.Fatalf("unexpected details %q and %q", rows[0].Detail, rows[1].Detail)
+	}
+}
+
+func TestImportStopsOnUnreadableResults(t *testing.T) {
+	n := newNode(t)
+	defer n.close()
+	n.corrupt = true
+
+	rows := readCSV(t,
+		"student_id,competence_id,semester,secret",
+		"6030001,1,1,"+secret,
+		"6030002,1,1,"+secret,
+	)
+
+	im, err := New(n.client(), Config{Keys: []ed25519.PrivateKey{n.admin}, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := im.Validate(rows); err != nil {
+		t.Fatal(err)
+	}
+
+	n.deliverJSON("GiveBadge", rows[0].Badge)
+
+	if err := im.Import(rows); err == nil {
+		t.Fatal("import went on after unreadable batch results")
+	}
+	if rows[0].Status != StatusFailed || rows[1].Status != "" {
+		t.Fatalf("expected the import to stop at row 1, got %v", statuses(rows))
+	}
+}
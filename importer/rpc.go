@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a client of a Tendermint node's JSON-RPC over HTTP
+type Client struct {
+	node string
+	http *http.Client
+}
+
+// NewClient return a client of the node at addr, e.g. http://localhost:26657
+func NewClient(addr string) *Client {
+	return &Client{
+		node: strings.TrimRight(addr, "/"),
+		http: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+func (c *Client) call(method string, params url.Values, result interface{}) error {
+	resp, err := c.http.Get(fmt.Sprintf("%s/%s?%s", c.node, method, params.Encode()))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("%s: %v", method, err)
+	}
+
+	if res.Error != nil {
+		return fmt.Errorf("%s: %s %s", method, res.Error.Message, res.Error.Data)
+	}
+
+	return json.Unmarshal(res.Result, result)
+}
+
+// ChainID return the chain ID of the node
+func (c *Client) ChainID() (string, error) {
+	var status struct {
+		NodeInfo struct {
+			Network string `json:"network"`
+		} `json:"node_info"`
+	}
+	if err := c.call("status", url.Values{}, &status); err != nil {
+		return "", err
+	}
+
+	return status.NodeInfo.Network, nil
+}
+
+// Query run an ABCI query and return its value, nil when it does not exist
+func (c *Client) Query(path string, data []byte) ([]byte, error) {
+	var result struct {
+		Response struct {
+			Code  uint32 `json:"code"`
+			Log   string `json:"log"`
+			Value []byte `json:"value"`
+		} `json:"response"`
+	}
+
+	params := url.Values{}
+	params.Set("path", strconv.Quote(path))
+	params.Set("data", "0x"+hex.EncodeToString(data))
+	if err := c.call("abci_query", params, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Response.Code != 0 {
+		return nil, fmt.Errorf("query %s: %s", path, result.Response.Log)
+	}
+
+	return result.Response.Value, nil
+}
+
+// TxResult is the result of a transaction committed in a block
+type TxResult struct {
+	Code uint32 `json:"code"`
+	Data []byte `json:"data"`
+	Log  string `json:"log"`
+}
+
+// BroadcastResult is the result of broadcast_tx_commit
+type BroadcastResult struct {
+	CheckTx   TxResult `json:"check_tx"`
+	DeliverTx TxResult `json:"deliver_tx"`
+	Hash      string   `json:"hash"`
+	Height    string   `json:"height"`
+}
+
+// Broadcast send a transaction and wait until it is committed
+func (c *Client) Broadcast(tx []byte) (*BroadcastResult, error) {
+	params := url.Values{}
+	params.Set("tx", "0x"+hex.EncodeToString(tx))
+
+	var result BroadcastResult
+	if err := c.call("broadcast_tx_commit", params, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
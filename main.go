@@ -3,7 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	abciserver "github.com/tendermint/tendermint/abci/server"
@@ -11,20 +13,87 @@ import (
 	"github.com/tendermint/tendermint/libs/log"
 
 	sitcomapp "github.com/saguywalker/sitcomchain/app"
+	"github.com/saguywalker/sitcomchain/blobstore"
+	"github.com/saguywalker/sitcomchain/pii"
 )
 
-var socketAddr string
+var (
+	socketAddr string
+	piiDir     string
+	piiAddr    string
+	piiToken   string
+	blobAddr   string
+	blobDir    string
+	blobToken  string
+)
 
 func init() {
 	flag.StringVar(&socketAddr, "socket-addr", "tcp://0.0.0.0:26658", "socket address")
+	flag.StringVar(&piiDir, "pii-dir", "", "directory of the off-chain personal data store, disabled when empty")
+	flag.StringVar(&piiAddr, "pii-addr", "", "listen address of the personal data store, disabled when empty")
+	flag.StringVar(&piiToken, "pii-token", os.Getenv("PII_TOKEN"), "bearer token of the personal data store, requests are refused when empty")
+	flag.StringVar(&blobAddr, "blob-addr", "", "listen address of the evidence blob store, disabled when empty")
+	flag.StringVar(&blobDir, "blob-dir", "blobs", "directory of the evidence blob store")
+	flag.StringVar(&blobToken, "blob-token", os.Getenv("BLOB_TOKEN"), "bearer token of blob uploads, uploads are refused when empty")
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:]))
+	}
+
 	logger := logrus.New()
 	app := sitcomapp.NewSitcomApp("data", logger)
 
 	flag.Parse()
 
+	if piiDir != "" {
+		store, err := pii.NewStore(piiDir)
+		if err != nil {
+			panic(err)
+		}
+		defer store.Close()
+		app.SetPIIStore(store)
+
+		if piiAddr != "" {
+			piiServer := &http.Server{
+				Addr:              piiAddr,
+				Handler:           store.Handler(piiToken),
+				ReadHeaderTimeout: 10 * time.Second,
+				ReadTimeout:       time.Minute,
+				WriteTimeout:      time.Minute,
+				IdleTimeout:       2 * time.Minute,
+			}
+			go func() {
+				if err := piiServer.ListenAndServe(); err != nil {
+					fmt.Fprintf(os.Stderr, "error starting personal data store: %v", err)
+					os.Exit(1)
+				}
+			}()
+		}
+	}
+
+	if blobAddr != "" {
+		blobs, err := blobstore.NewStore(blobDir)
+		if err != nil {
+			panic(err)
+		}
+		blobServer := &http.Server{
+			Addr:              blobAddr,
+			Handler:           blobs.Handler(blobToken),
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       time.Minute,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       2 * time.Minute,
+		}
+		go func() {
+			if err := blobServer.ListenAndServe(); err != nil {
+				fmt.Fprintf(os.Stderr, "error starting blob store: %v", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	loggerTm := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
 
 	// server := abciserver.NewSocketServer(socketAddr, app)
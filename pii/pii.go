@@ -0,0 +1,146 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+const (
+	// KeySize is the size of a student's AES-256 key
+	KeySize = 32
+
+	keyPrefix    = "key:"
+	recordPrefix = "record:"
+)
+
+// ErrForgotten is returned when the key of a student has been deleted
+var ErrForgotten = errors.New("student has been forgotten")
+
+// Store is an off-chain store of personal data. Every student's records are
+// encrypted with a key of their own, so deleting the key erases every record
+// of the student, including copies left in backups of the records.
+type Store struct {
+	db dbm.DB
+}
+
+// NewStore open a store in dir
+func NewStore(dir string) (*Store, error) {
+	db, err := dbm.NewGoLevelDB("sitcompii", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// NewStoreWithDB return a store using db
+func NewStoreWithDB(db dbm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Hash return the reference of an encrypted record, sha256 in hex
+func Hash(ciphertext []byte) string {
+	hashed := sha256.Sum256(ciphertext)
+	return hex.EncodeToString(hashed[:])
+}
+
+func (s *Store) key(studentID string, create bool) ([]byte, error) {
+	key := s.db.Get([]byte(keyPrefix + studentID))
+	if key != nil {
+		return key, nil
+	}
+
+	if !create {
+		return nil, ErrForgotten
+	}
+
+	key = make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	s.db.SetSync([]byte(keyPrefix+studentID), key)
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Put encrypt a record of a student and return its reference to be kept on chain
+func (s *Store) Put(studentID string, record []byte) (string, error) {
+	key, err := s.key(studentID, true)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, record, []byte(studentID))
+	ref := Hash(ciphertext)
+	s.db.SetSync([]byte(recordPrefix+ref), ciphertext)
+	return ref, nil
+}
+
+// Get decrypt the record of a student by its reference
+func (s *Store) Get(studentID string, ref string) ([]byte, error) {
+	ciphertext := s.db.Get([]byte(recordPrefix + ref))
+	if ciphertext == nil {
+		return nil, fmt.Errorf("record %s does not exist", ref)
+	}
+
+	if Hash(ciphertext) != ref {
+		return nil, fmt.Errorf("record %s does not match its hash", ref)
+	}
+
+	key, err := s.key(studentID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("record %s is too short", ref)
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, []byte(studentID))
+}
+
+// Forget delete the key of a student, every record of the student can no
+// longer be decrypted. The record with ref, if given, is deleted as well.
+func (s *Store) Forget(studentID string, ref string) {
+	s.db.DeleteSync([]byte(keyPrefix + studentID))
+	if ref != "" {
+		s.db.DeleteSync([]byte(recordPrefix + ref))
+	}
+}
+
+// Close close the store
+func (s *Store) Close() {
+	s.db.Close()
+}
@@ -0,0 +1,115 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+const token = "a token of the registrar"
+
+func TestPutGetForget(t *testing.T) {
+	store := NewStoreWithDB(dbm.NewMemDB())
+
+	first, err := store.Put("student", []byte("name: Somchai"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.Put("student", []byte("email: somchai@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := store.Get("student", first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(record) != "name: Somchai" {
+		t.Fatalf("expected the stored record, got %q", record)
+	}
+	if _, err := store.Get("another student", first); err == nil {
+		t.Fatal("record decrypts as another student's")
+	}
+
+	store.Forget("student", first)
+	if _, err := store.Get("student", first); err == nil {
+		t.Fatal("forgotten record can still be read")
+	}
+	// the record is left, but its key is gone
+	if _, err := store.Get("student", second); err != ErrForgotten {
+		t.Fatalf("expected %v, got %v", ErrForgotten, err)
+	}
+}
+
+func TestGetTamperedRecord(t *testing.T) {
+	db := dbm.NewMemDB()
+	store := NewStoreWithDB(db)
+
+	ref, err := store.Put("student", []byte("name: Somchai"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext := db.Get([]byte(recordPrefix + ref))
+	ciphertext[len(ciphertext)-1] ^= 1
+	db.Set([]byte(recordPrefix+ref), ciphertext)
+	if _, err := store.Get("student", ref); err == nil {
+		t.Fatal("tampered record can be read")
+	}
+}
+
+func request(handler http.Handler, method string, path string, authorization string, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	store := NewStoreWithDB(dbm.NewMemDB())
+	handler := store.Handler(token)
+
+	for _, authorization := range []string{"", token, "Bearer wrong", "Basic " + token} {
+		if w := request(handler, http.MethodPost, RecordsPath+"student", authorization, []byte("name: Somchai")); w.Code != http.StatusUnauthorized {
+			t.Fatalf("authorization %q: expected status %d, got %d", authorization, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	w := request(handler, http.MethodPost, RecordsPath+"student", "Bearer "+token, []byte("name: Somchai"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+
+	var result PutResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := request(handler, http.MethodGet, RecordsPath+"student/"+result.Ref, "", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("read without a token: expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := request(handler, http.MethodGet, RecordsPath+"student/"+result.Ref, "Bearer "+token, nil); w.Code != http.StatusOK || w.Body.String() != "name: Somchai" {
+		t.Fatalf("expected the record, got %d: %s", w.Code, w.Body)
+	}
+
+	if w := request(handler, http.MethodPost, RecordsPath+"student", "Bearer "+token, make([]byte, MaxRecordSize+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("record above the limit: expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	store.Forget("student", "")
+	if w := request(handler, http.MethodGet, RecordsPath+"student/"+result.Ref, "Bearer "+token, nil); w.Code != http.StatusGone {
+		t.Fatalf("forgotten record: expected status %d, got %d", http.StatusGone, w.Code)
+	}
+
+	if w := request(NewStoreWithDB(dbm.NewMemDB()).Handler(""), http.MethodPost, RecordsPath+"student", "Bearer ", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("empty token: expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,102 @@
+package pii
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	// RecordsPath is the path of the record endpoints
+	RecordsPath = "/records/"
+
+	// MaxRecordSize is the maximum size of a record
+	MaxRecordSize = 1 << 20
+)
+
+// PutResult is the response of a stored record, Ref is the pii_hash of SetStudentRecord
+type PutResult struct {
+	Ref string `json:"ref"`
+}
+
+// Handler return an HTTP handler of the store, POST /records/<student_id>
+// encrypt the body as a record of the student and return its reference,
+// GET /records/<student_id>/<ref> return the decrypted record.
+// Every request needs the token as a bearer token, requests are refused
+// when it is empty.
+func (s *Store) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RecordsPath, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, RecordsPath), "/")
+		switch {
+		case r.Method == http.MethodPost && len(parts) == 1 && parts[0] != "":
+			s.servePut(w, r, parts[0])
+		case r.Method == http.MethodGet && len(parts) == 2 && parts[0] != "" && parts[1] != "":
+			s.serveGet(w, r, parts[0], parts[1])
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	return mux
+}
+
+// authorized report whether a request carries the token as a bearer token
+func authorized(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+
+	given := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (s *Store) servePut(w http.ResponseWriter, r *http.Request, studentID string) {
+	defer r.Body.Close()
+
+	record, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxRecordSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ref, err := s.Put(studentID, record)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(PutResult{Ref: ref})
+}
+
+func (s *Store) serveGet(w http.ResponseWriter, r *http.Request, studentID string, ref string) {
+	if !s.db.Has([]byte(recordPrefix + ref)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	record, err := s.Get(studentID, ref)
+	if err == ErrForgotten {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(record)
+}
@@ -21,11 +21,12 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type Tx struct {
-	Payload              *Payload `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
-	Signature            []byte   `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Payload              *Payload     `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	Signature            []byte       `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Signatures           []*Signature `protobuf:"bytes,3,rep,name=signatures,proto3" json:"signatures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *Tx) Reset()         { *m = Tx{} }
@@ -67,9 +68,74 @@ func (m *Tx) GetSignature() []byte {
 	return nil
 }
 
+func (m *Tx) GetSignatures() []*Signature {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+type Signature struct {
+	PublicKey            []byte   `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Signature            []byte   `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	KeyType              string   `protobuf:"bytes,3,opt,name=key_type,json=keyType,proto3" json:"key_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Signature) Reset()         { *m = Signature{} }
+func (m *Signature) String() string { return proto.CompactTextString(m) }
+func (*Signature) ProtoMessage()    {}
+func (*Signature) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04f926c8da23c367, []int{1}
+}
+
+func (m *Signature) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Signature.Unmarshal(m, b)
+}
+func (m *Signature) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Signature.Marshal(b, m, deterministic)
+}
+func (m *Signature) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Signature.Merge(m, src)
+}
+func (m *Signature) XXX_Size() int {
+	return xxx_messageInfo_Signature.Size(m)
+}
+func (m *Signature) XXX_DiscardUnknown() {
+	xxx_messageInfo_Signature.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Signature proto.InternalMessageInfo
+
+func (m *Signature) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *Signature) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *Signature) GetKeyType() string {
+	if m != nil {
+		return m.KeyType
+	}
+	return ""
+}
+
 type Payload struct {
 	Method               string   `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
 	Params               []byte   `protobuf:"bytes,2,opt,name=params,proto3" json:"params,omitempty"`
+	ChainId              string   `protobuf:"bytes,3,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	Nonce                []byte   `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Version              uint32   `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -79,7 +145,7 @@ func (m *Payload) Reset()         { *m = Payload{} }
 func (m *Payload) String() string { return proto.CompactTextString(m) }
 func (*Payload) ProtoMessage()    {}
 func (*Payload) Descriptor() ([]byte, []int) {
-	return fileDescriptor_04f926c8da23c367, []int{1}
+	return fileDescriptor_04f926c8da23c367, []int{2}
 }
 
 func (m *Payload) XXX_Unmarshal(b []byte) error {
@@ -114,6 +180,27 @@ func (m *Payload) GetParams() []byte {
 	return nil
 }
 
+func (m *Payload) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *Payload) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *Payload) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 type Query struct {
 	Method               string   `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
 	Params               string   `protobuf:"bytes,2,opt,name=params,proto3" json:"params,omitempty"`
@@ -126,7 +213,7 @@ func (m *Query) Reset()         { *m = Query{} }
 func (m *Query) String() string { return proto.CompactTextString(m) }
 func (*Query) ProtoMessage()    {}
 func (*Query) Descriptor() ([]byte, []int) {
-	return fileDescriptor_04f926c8da23c367, []int{2}
+	return fileDescriptor_04f926c8da23c367, []int{3}
 }
 
 func (m *Query) XXX_Unmarshal(b []byte) error {
@@ -161,24 +248,122 @@ func (m *Query) GetParams() string {
 	return ""
 }
 
+type Message struct {
+	Payload              *Payload     `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	Signatures           []*Signature `protobuf:"bytes,2,rep,name=signatures,proto3" json:"signatures,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+func (*Message) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04f926c8da23c367, []int{4}
+}
+
+func (m *Message) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Message.Unmarshal(m, b)
+}
+func (m *Message) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Message.Marshal(b, m, deterministic)
+}
+func (m *Message) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message.Merge(m, src)
+}
+func (m *Message) XXX_Size() int {
+	return xxx_messageInfo_Message.Size(m)
+}
+func (m *Message) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message proto.InternalMessageInfo
+
+func (m *Message) GetPayload() *Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *Message) GetSignatures() []*Signature {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+type Messages struct {
+	Messages             []*Message `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *Messages) Reset()         { *m = Messages{} }
+func (m *Messages) String() string { return proto.CompactTextString(m) }
+func (*Messages) ProtoMessage()    {}
+func (*Messages) Descriptor() ([]byte, []int) {
+	return fileDescriptor_04f926c8da23c367, []int{5}
+}
+
+func (m *Messages) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Messages.Unmarshal(m, b)
+}
+func (m *Messages) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Messages.Marshal(b, m, deterministic)
+}
+func (m *Messages) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Messages.Merge(m, src)
+}
+func (m *Messages) XXX_Size() int {
+	return xxx_messageInfo_Messages.Size(m)
+}
+func (m *Messages) XXX_DiscardUnknown() {
+	xxx_messageInfo_Messages.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Messages proto.InternalMessageInfo
+
+func (m *Messages) GetMessages() []*Message {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Tx)(nil), "Tx")
+	proto.RegisterType((*Signature)(nil), "Signature")
 	proto.RegisterType((*Payload)(nil), "Payload")
 	proto.RegisterType((*Query)(nil), "Query")
+	proto.RegisterType((*Message)(nil), "Message")
+	proto.RegisterType((*Messages)(nil), "Messages")
 }
 
 func init() { proto.RegisterFile("tendermint.proto", fileDescriptor_04f926c8da23c367) }
 
 var fileDescriptor_04f926c8da23c367 = []byte{
-	// 148 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x28, 0x49, 0xcd, 0x4b,
-	0x49, 0x2d, 0xca, 0xcd, 0xcc, 0x2b, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0x72, 0xe3, 0x62,
-	0x0a, 0xa9, 0x10, 0x52, 0xe2, 0x62, 0x2f, 0x48, 0xac, 0xcc, 0xc9, 0x4f, 0x4c, 0x91, 0x60, 0x54,
-	0x60, 0xd4, 0xe0, 0x36, 0xe2, 0xd0, 0x0b, 0x80, 0xf0, 0x83, 0x60, 0x12, 0x42, 0x32, 0x5c, 0x9c,
-	0xc5, 0x99, 0xe9, 0x79, 0x89, 0x25, 0xa5, 0x45, 0xa9, 0x12, 0x4c, 0x0a, 0x8c, 0x1a, 0x3c, 0x41,
-	0x08, 0x01, 0x25, 0x4b, 0x2e, 0x76, 0xa8, 0x0e, 0x21, 0x31, 0x2e, 0xb6, 0xdc, 0xd4, 0x92, 0x8c,
-	0x7c, 0x88, 0x59, 0x9c, 0x41, 0x50, 0x1e, 0x48, 0xbc, 0x20, 0xb1, 0x28, 0x31, 0xb7, 0x18, 0xaa,
-	0x1b, 0xca, 0x53, 0x32, 0xe7, 0x62, 0x0d, 0x2c, 0x4d, 0x2d, 0xaa, 0x24, 0x52, 0x23, 0x27, 0x4c,
-	0x63, 0x12, 0x1b, 0xd8, 0x0b, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x82, 0x63, 0xcd, 0x59,
-	0xd6, 0x00, 0x00, 0x00,
+	// 303 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xcf, 0x4b, 0xfb, 0x30,
+	0x18, 0xc6, 0xc9, 0xf6, 0xdd, 0xda, 0xbe, 0xdb, 0x17, 0x24, 0x88, 0x44, 0x50, 0x28, 0xc5, 0x43,
+	0xf1, 0x50, 0x64, 0x1e, 0xfc, 0x1b, 0x44, 0x04, 0x8d, 0xbb, 0x78, 0x1a, 0x59, 0xfb, 0xb2, 0x95,
+	0xad, 0x49, 0x48, 0x32, 0x31, 0x77, 0xff, 0x70, 0x59, 0x9b, 0x4e, 0xbd, 0xe8, 0x6e, 0xfd, 0x3c,
+	0x0f, 0xef, 0xf3, 0xfe, 0x68, 0xe0, 0xc4, 0xa1, 0xac, 0xd0, 0x34, 0xb5, 0x74, 0x85, 0x36, 0xca,
+	0xa9, 0xcc, 0xc0, 0x60, 0xfe, 0x4e, 0x33, 0x88, 0xb4, 0xf0, 0x5b, 0x25, 0x2a, 0x46, 0x52, 0x92,
+	0x4f, 0x66, 0x71, 0xf1, 0xd4, 0x31, 0xef, 0x0d, 0x7a, 0x01, 0x89, 0xad, 0x57, 0x52, 0xb8, 0x9d,
+	0x41, 0x36, 0x48, 0x49, 0x3e, 0xe5, 0x5f, 0x02, 0xbd, 0x06, 0x38, 0x80, 0x65, 0xc3, 0x74, 0x98,
+	0x4f, 0x66, 0x50, 0xbc, 0xf4, 0x12, 0xff, 0xe6, 0x66, 0x25, 0x24, 0x07, 0x83, 0x5e, 0x02, 0xe8,
+	0xdd, 0x72, 0x5b, 0x97, 0x8b, 0x0d, 0xfa, 0xb6, 0xfb, 0x94, 0x27, 0x9d, 0xf2, 0x80, 0xfe, 0x8f,
+	0xae, 0xe7, 0x10, 0x6f, 0xd0, 0x2f, 0x9c, 0xd7, 0xc8, 0x86, 0x29, 0xc9, 0x13, 0x1e, 0x6d, 0xd0,
+	0xcf, 0xbd, 0xc6, 0xec, 0x83, 0x40, 0x14, 0x76, 0xa0, 0x67, 0x30, 0x6e, 0xd0, 0xad, 0x55, 0xb7,
+	0x5d, 0xc2, 0x03, 0xed, 0x75, 0x2d, 0x8c, 0x68, 0x6c, 0x48, 0x0e, 0xb4, 0x8f, 0x2d, 0xd7, 0xa2,
+	0x96, 0x8b, 0xba, 0xea, 0x63, 0x5b, 0xbe, 0xaf, 0xe8, 0x29, 0x8c, 0xa4, 0x92, 0x25, 0xb2, 0x7f,
+	0x6d, 0x45, 0x07, 0x94, 0x41, 0xf4, 0x86, 0xc6, 0xd6, 0x4a, 0xb2, 0x51, 0x4a, 0xf2, 0xff, 0xbc,
+	0xc7, 0xec, 0x0e, 0x46, 0xcf, 0x3b, 0x34, 0xfe, 0xc8, 0x19, 0x92, 0x7e, 0x86, 0xec, 0x15, 0xa2,
+	0x47, 0xb4, 0x56, 0xac, 0xf0, 0xa8, 0xbf, 0xf3, 0xf3, 0xfe, 0x83, 0x5f, 0xef, 0x7f, 0x03, 0x71,
+	0x88, 0xb6, 0xf4, 0x0a, 0xe2, 0x26, 0x7c, 0x33, 0xd2, 0x56, 0xc5, 0x45, 0x30, 0xf9, 0xc1, 0x59,
+	0x8e, 0xdb, 0xc7, 0x72, 0xfb, 0x39, 0x00, 0xfa, 0x3f, 0xeb, 0xc7, 0x40, 0x02, 0x00, 0x00,
 }
@@ -0,0 +1,236 @@
+// Package protocol holds what a transaction's signers and a badge's
+// verifiers must compute alike: badge keys, sign bytes and signatures.
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+	utils "github.com/saguywalker/sitcomchain/util"
+)
+
+const (
+	// KeyTypeEd25519 is the default key type
+	KeyTypeEd25519 = "ed25519"
+
+	// KeyTypeSecp256k1 is a compressed secp256k1 key
+	KeyTypeSecp256k1 = "secp256k1"
+
+	// GiveBadgeMethod is the method of a single badge
+	GiveBadgeMethod = "GiveBadge"
+
+	// GiveBadgeBatchMethod is the method of a transaction whose params are
+	// {"entries": [...]}, each entry the params of a GiveBadge
+	GiveBadgeBatchMethod = "GiveBadgeBatch"
+
+	// MultiMessageMethod is the method of a transaction whose params are
+	// protobuf encoded Messages
+	MultiMessageMethod = "MultiMessage"
+)
+
+// Step is one level of a payload inside a batch or multi-message: the
+// container's payload without its params and the proof of the item's
+// digest in the merkle tree of the container's items
+type Step struct {
+	Payload *protoTm.Payload   `json:"payload"`
+	Proof   merkle.SimpleProof `json:"proof"`
+}
+
+// BadgeKey return the on-chain key of a badge, a JSON object of student,
+// competence and semester with sorted keys
+func BadgeKey(studentID string, competenceID uint32, semester uint32) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"student_id":    studentID,
+		"competence_id": competenceID,
+		"semester":      semester,
+	})
+}
+
+// payloadHash return the hash of the deterministic encoding of a payload
+// with params, without its signatures
+func payloadHash(payload *protoTm.Payload, params []byte) ([]byte, error) {
+	canonical := protoTm.Payload{
+		Method:  payload.Method,
+		Params:  params,
+		ChainId: payload.ChainId,
+		Nonce:   payload.Nonce,
+		Version: payload.Version,
+	}
+
+	encoded, err := utils.ProtoDeterministicMarshal(&canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := sha256.Sum256(encoded)
+	return hashed[:], nil
+}
+
+// items return the payloads a batch or multi-message carries and their sign bytes,
+// an entry of a batch is the payload of a GiveBadge with the entry as params
+func items(payload *protoTm.Payload) ([]*protoTm.Payload, [][]byte, error) {
+	children := make([]*protoTm.Payload, 0)
+	switch payload.Method {
+	case GiveBadgeBatchMethod:
+		var batch struct {
+			Entries []json.RawMessage `json:"entries"`
+		}
+		if err := json.Unmarshal(payload.Params, &batch); err != nil {
+			return nil, nil, err
+		}
+		for _, entry := range batch.Entries {
+			children = append(children, &protoTm.Payload{Method: GiveBadgeMethod, Params: entry})
+		}
+	case MultiMessageMethod:
+		var messages protoTm.Messages
+		if err := proto.Unmarshal(payload.Params, &messages); err != nil {
+			return nil, nil, err
+		}
+		for i, msg := range messages.Messages {
+			if msg.Payload == nil {
+				return nil, nil, fmt.Errorf("message %d has no payload", i)
+			}
+			children = append(children, msg.Payload)
+		}
+	default:
+		return nil, nil, fmt.Errorf("%s carries no payloads", payload.Method)
+	}
+
+	digests := make([][]byte, 0, len(children))
+	for _, child := range children {
+		digest, err := SignBytes(child)
+		if err != nil {
+			return nil, nil, err
+		}
+		digests = append(digests, digest)
+	}
+
+	return children, digests, nil
+}
+
+// isContainer report whether the payload of a method carries other payloads
+func isContainer(method string) bool {
+	return method == GiveBadgeBatchMethod || method == MultiMessageMethod
+}
+
+// SignBytes return the bytes a transaction's signers sign, the hash of the
+// deterministic encoding of the payload without its signatures. The params of
+// a batch or multi-message are replaced by the merkle root of their items'
+// sign bytes, so one item can be proven signed without the others.
+func SignBytes(payload *protoTm.Payload) ([]byte, error) {
+	if !isContainer(payload.Method) {
+		return payloadHash(payload, payload.Params)
+	}
+
+	_, digests, err := items(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payloadHash(payload, merkle.SimpleHashFromByteSlices(digests))
+}
+
+// Prove return the payload at a location in a transaction's payload, the
+// index of an item at each level, with the steps from it up to the transaction
+func Prove(payload *protoTm.Payload, location []int) (*protoTm.Payload, []Step, error) {
+	steps := make([]Step, 0, len(location))
+	for _, index := range location {
+		children, digests, err := items(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		if index < 0 || index >= len(children) {
+			return nil, nil, fmt.Errorf("%s has no item %d", payload.Method, index)
+		}
+
+		_, proofs := merkle.SimpleProofsFromByteSlices(digests)
+		header := *payload
+		header.Params = nil
+		steps = append([]Step{{Payload: &header, Proof: *proofs[index]}}, steps...)
+		payload = children[index]
+	}
+
+	return payload, steps, nil
+}
+
+// Digest return the sign bytes of the transaction a payload is in, computed
+// from the payload and the steps from it up to the transaction
+func Digest(payload *protoTm.Payload, steps []Step) ([]byte, error) {
+	if payload == nil {
+		return nil, errors.New("missing payload")
+	}
+
+	digest, err := SignBytes(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range steps {
+		if step.Payload == nil || !isContainer(step.Payload.Method) {
+			return nil, errors.New("a step must be a batch or multi-message")
+		}
+
+		if err := step.Proof.ValidateBasic(); err != nil {
+			return nil, err
+		}
+
+		root := step.Proof.ComputeRootHash()
+		if root == nil {
+			return nil, errors.New("invalid merkle proof")
+		}
+		if err := step.Proof.Verify(root, digest); err != nil {
+			return nil, err
+		}
+
+		if digest, err = payloadHash(step.Payload, root); err != nil {
+			return nil, err
+		}
+	}
+
+	return digest, nil
+}
+
+// ValidatePublicKey check that a public key matches its declared type,
+// an empty key type means ed25519
+func ValidatePublicKey(keyType string, publicKey []byte) error {
+	switch keyType {
+	case "", KeyTypeEd25519:
+		if len(publicKey) != ed25519.PubKeyEd25519Size {
+			return fmt.Errorf("invalid ed25519 public key size: %d", len(publicKey))
+		}
+	case KeyTypeSecp256k1:
+		if len(publicKey) != secp256k1.PubKeySecp256k1Size {
+			return fmt.Errorf("invalid secp256k1 public key size: %d", len(publicKey))
+		}
+	default:
+		return fmt.Errorf("unsupported key type: %s", keyType)
+	}
+
+	return nil
+}
+
+// VerifySignature verify a signature with an algorithm of the key type,
+// an empty key type means ed25519
+func VerifySignature(keyType string, publicKey, msg, signature []byte) bool {
+	if ValidatePublicKey(keyType, publicKey) != nil {
+		return false
+	}
+
+	if keyType == KeyTypeSecp256k1 {
+		var pubKey secp256k1.PubKeySecp256k1
+		copy(pubKey[:], publicKey)
+		return pubKey.VerifyBytes(msg, signature)
+	}
+
+	var pubKey ed25519.PubKeyEd25519
+	copy(pubKey[:], publicKey)
+	return pubKey.VerifyBytes(msg, signature)
+}
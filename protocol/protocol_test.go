@@ -0,0 +1,134 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	protoTm "github.com/saguywalker/sitcomchain/proto/tendermint"
+)
+
+func batch(t *testing.T, students ...string) *protoTm.Payload {
+	entries := make([]json.RawMessage, 0, len(students))
+	for _, studentID := range students {
+		entries = append(entries, json.RawMessage(fmt.Sprintf(`{"student_id":%q,"competence_id":1,"semester":1}`, studentID)))
+	}
+
+	params, err := json.Marshal(map[string]interface{}{"entries": entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &protoTm.Payload{Method: GiveBadgeBatchMethod, Params: params, ChainId: "test-chain", Nonce: []byte("batch"), Version: 1}
+}
+
+func multiMessage(t *testing.T, payloads ...*protoTm.Payload) *protoTm.Payload {
+	messages := make([]*protoTm.Message, 0, len(payloads))
+	for _, payload := range payloads {
+		messages = append(messages, &protoTm.Message{Payload: payload})
+	}
+
+	params, err := proto.Marshal(&protoTm.Messages{Messages: messages})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &protoTm.Payload{Method: MultiMessageMethod, Params: params, ChainId: "test-chain", Nonce: []byte("multi"), Version: 1}
+}
+
+func TestProveDigest(t *testing.T) {
+	activity := &protoTm.Payload{Method: "CreateActivity", Params: []byte(`{"id":1}`)}
+	tx := multiMessage(t, activity, batch(t, "a", "b", "c"))
+
+	signBytes, err := SignBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, location := range [][]int{{0}, {1}, {1, 0}, {1, 2}} {
+		payload, steps, err := Prove(tx, location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(steps) != len(location) {
+			t.Fatalf("location %v: expected %d steps, got %d", location, len(location), len(steps))
+		}
+
+		digest, err := Digest(payload, steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(digest, signBytes) {
+			t.Fatalf("location %v does not lead to the sign bytes of the transaction", location)
+		}
+	}
+
+	entry, _, err := Prove(tx, []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Method != GiveBadgeMethod || !bytes.Contains(entry.Params, []byte(`"c"`)) {
+		t.Fatalf("expected the entry of student c, got %s %s", entry.Method, entry.Params)
+	}
+
+	if _, _, err := Prove(tx, []int{2}); err == nil {
+		t.Fatal("proved an item out of range")
+	}
+	if _, _, err := Prove(tx, []int{0, 0}); err == nil {
+		t.Fatal("proved an item of a payload which carries none")
+	}
+}
+
+func TestDigestRejectsTamperedSteps(t *testing.T) {
+	tx := batch(t, "a", "b", "c")
+	signBytes, err := SignBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, steps, err := Prove(tx, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, err := Prove(tx, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Digest(other, steps); err == nil {
+		t.Fatal("another entry verifies with the steps of entry 1")
+	}
+
+	steps[0].Payload.Nonce = []byte("another")
+	if digest, err := Digest(payload, steps); err == nil && bytes.Equal(digest, signBytes) {
+		t.Fatal("a step of another nonce leads to the same sign bytes")
+	}
+
+	steps[0].Payload.Method = "CreateActivity"
+	if _, err := Digest(payload, steps); err == nil {
+		t.Fatal("a step which is not a batch or multi-message verifies")
+	}
+}
+
+func TestSignBytesOfBatchCoversEntries(t *testing.T) {
+	first, err := SignBytes(batch(t, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := SignBytes(batch(t, "a", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(first, second) {
+		t.Fatal("batches of different entries have the same sign bytes")
+	}
+
+	if _, err := SignBytes(&protoTm.Payload{Method: GiveBadgeBatchMethod, Params: []byte("not json")}); err == nil {
+		t.Fatal("sign bytes of a malformed batch")
+	}
+}
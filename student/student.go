@@ -0,0 +1,60 @@
+package student
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	// MinSecretSize is the minimum size of a disclosure secret, so a commitment
+	// cannot be brute forced from the small space of student IDs
+	MinSecretSize = 16
+
+	commitmentDomain = "sitcomchain/student:"
+)
+
+// Commit return a pseudonymous on-chain ID of a student, sha256 over the
+// student ID and the student's disclosure secret in hex
+func Commit(studentID string, secret []byte) (string, error) {
+	if studentID == "" {
+		return "", fmt.Errorf("student id cannot be empty")
+	}
+
+	if len(secret) < MinSecretSize {
+		return "", fmt.Errorf("secret must be at least %d bytes", MinSecretSize)
+	}
+
+	hasher := sha256.New()
+	hasher.Write([]byte(commitmentDomain))
+	hasher.Write([]byte(fmt.Sprintf("%d:%s:", len(studentID), studentID)))
+	hasher.Write(secret)
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Verify check that a commitment was made from the student ID and secret
+func Verify(commitment, studentID string, secret []byte) bool {
+	expected, err := Commit(studentID, secret)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(commitment)) == 1
+}
+
+// IsCommitment report whether an on-chain student ID is a commitment
+// rather than a plaintext student ID
+func IsCommitment(id string) bool {
+	if len(id) != sha256.Size*2 {
+		return false
+	}
+
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
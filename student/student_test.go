@@ -0,0 +1,68 @@
+package student
+
+import (
+	"strings"
+	"testing"
+)
+
+var secret = []byte("0123456789abcdef")
+
+func TestCommitVerify(t *testing.T) {
+	commitment, err := Commit("6030123", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsCommitment(commitment) {
+		t.Fatalf("%s is not a commitment", commitment)
+	}
+	if !Verify(commitment, "6030123", secret) {
+		t.Fatal("commitment does not verify with its student ID and secret")
+	}
+	if Verify(commitment, "6030124", secret) {
+		t.Fatal("commitment verifies with another student ID")
+	}
+	if Verify(commitment, "6030123", []byte("fedcba9876543210")) {
+		t.Fatal("commitment verifies with another secret")
+	}
+}
+
+func TestCommitSeparatesStudentIDAndSecret(t *testing.T) {
+	first, err := Commit("1", []byte("23456789abcdef0123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := Commit("12", []byte("3456789abcdef0123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Fatal("commitments of different student IDs collide")
+	}
+}
+
+func TestCommitRejectsInvalidInput(t *testing.T) {
+	if _, err := Commit("", secret); err == nil {
+		t.Fatal("commitment of an empty student ID")
+	}
+
+	if _, err := Commit("6030123", secret[:MinSecretSize-1]); err == nil {
+		t.Fatal("commitment with a short secret")
+	}
+}
+
+func TestIsCommitment(t *testing.T) {
+	for id, expected := range map[string]bool{
+		strings.Repeat("a1", 32): true,
+		strings.Repeat("A1", 32): false,
+		strings.Repeat("a1", 31): false,
+		strings.Repeat("g1", 32): false,
+		"6030123":                false,
+	} {
+		if IsCommitment(id) != expected {
+			t.Errorf("IsCommitment(%q) should be %v", id, expected)
+		}
+	}
+}